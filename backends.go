@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// backendFalseAlarmRate is the chance that CompareBackends() finds a difference in any of comparedStats between two backends that simulate the same model
+const backendFalseAlarmRate float64 = 0.01
+
+// BackendComparison compares one stat between the dense Ecosystem and the SparseEcosystem, see CompareBackends().
+type BackendComparison struct {
+	stat       string
+	denseMean  float64
+	denseSD    float64
+	sparseMean float64
+	sparseSD   float64
+	tStatistic float64 // Welch's t of the difference between the means
+	agree      bool
+}
+
+// comparedStats are the stats CompareBackends() compares, each averaged over all generations of a run.
+var comparedStats = []struct {
+	name  string
+	value func(GenerationStats) float64
+}{
+	{"numPrey", func(stats GenerationStats) float64 { return float64(stats.numPrey) }},
+	{"numPred", func(stats GenerationStats) float64 { return float64(stats.numPred) }},
+	{"numFood", func(stats GenerationStats) float64 { return float64(stats.numFood) }},
+	{"foodEaten", func(stats GenerationStats) float64 { return float64(stats.foodEaten) }},
+	{"preyEaten", func(stats GenerationStats) float64 { return float64(stats.preyEaten) }},
+}
+
+// CompareBackends() checks that the dense and the sparse backend simulate the same model. it runs numReplicates simulations with each backend
+// from the same settings, takes the mean of every stat in comparedStats over each run, and compares the two sets of run means with Welch's t-test.
+// the runs are random, so the backends only have to agree statistically: a stat agrees when |t| is below BackendAgreementThreshold().
+// every run draws from generator instead of rng, so a comparison with a seeded generator always gives the same result.
+func CompareBackends(numRows, numCols, numPrey, numPred, totalTimesteps int, foodRule string, numReplicates int, generator *rand.Rand) []BackendComparison {
+	if numReplicates < 2 {
+		panic("CompareBackends needs at least 2 replicates to estimate the spread of the runs")
+	}
+	previousRng := rng
+	rng = generator
+	defer func() { rng = previousRng }()
+
+	denseMeans := make([][]float64, len(comparedStats))
+	sparseMeans := make([][]float64, len(comparedStats))
+	for replicate := 0; replicate < numReplicates; replicate++ {
+		denseEcosystem := InitializeEcosystem(numRows, numCols, numPrey, numPred)
+		SimulateEcosystemEvolution(&denseEcosystem, totalTimesteps, foodRule)
+		AddRunMeans(denseMeans, allStats)
+
+		sparseEcosystem := InitializeSparseEcosystem(numRows, numCols, numPrey, numPred)
+		SimulateSparseEcosystemEvolution(sparseEcosystem, totalTimesteps, foodRule, totalTimesteps)
+		AddRunMeans(sparseMeans, allStats)
+	}
+
+	threshold := BackendAgreementThreshold()
+	comparisons := make([]BackendComparison, len(comparedStats))
+	for k, stat := range comparedStats {
+		comparison := BackendComparison{stat: stat.name}
+		comparison.denseMean, comparison.denseSD = MeanAndSD(denseMeans[k])
+		comparison.sparseMean, comparison.sparseSD = MeanAndSD(sparseMeans[k])
+
+		standardError := math.Sqrt((comparison.denseSD*comparison.denseSD + comparison.sparseSD*comparison.sparseSD) / float64(numReplicates))
+		if standardError > 0 {
+			comparison.tStatistic = (comparison.denseMean - comparison.sparseMean) / standardError
+			comparison.agree = math.Abs(comparison.tStatistic) < threshold
+		} else {
+			comparison.agree = comparison.denseMean == comparison.sparseMean
+		}
+		comparisons[k] = comparison
+	}
+	return comparisons
+}
+
+// BackendAgreementThreshold() is the largest Welch's t at which CompareBackends() still counts a stat as agreeing between the backends.
+// the comparedStats are tested separately, so it is Bonferroni-corrected: each test only gets backendFalseAlarmRate / len(comparedStats) of it.
+// with enough replicates t is about normal, so this is the two-sided normal quantile of that rate, e.g. 3.09 for 0.01 over 5 stats.
+func BackendAgreementThreshold() float64 {
+	perTestRate := backendFalseAlarmRate / float64(len(comparedStats))
+	return math.Sqrt2 * math.Erfinv(1-perTestRate)
+}
+
+// AddRunMeans() appends the mean of every stat in comparedStats over allStats of one run to runMeans.
+func AddRunMeans(runMeans [][]float64, allStats []GenerationStats) {
+	for k, stat := range comparedStats {
+		sum := 0.0
+		for _, stats := range allStats {
+			sum += stat.value(stats)
+		}
+		runMeans[k] = append(runMeans[k], sum/float64(len(allStats)))
+	}
+}
+
+// MeanAndSD() returns the mean and the sample standard deviation of values.
+func MeanAndSD(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+	sumSquares := 0.0
+	for _, value := range values {
+		sumSquares += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(sumSquares / float64(len(values)-1))
+}
+
+// PrintBackendComparison() prints a table of comparisons and returns true if every stat agrees.
+func PrintBackendComparison(comparisons []BackendComparison) bool {
+	allAgree := true
+	fmt.Printf("%-10s %22s %22s %8s\n", "stat", "dense mean (sd)", "sparse mean (sd)", "t")
+	for _, comparison := range comparisons {
+		verdict := "agree"
+		if !comparison.agree {
+			verdict = "DIFFER"
+			allAgree = false
+		}
+		fmt.Printf("%-10s %12.2f (%7.2f) %12.2f (%7.2f) %8.2f %s\n", comparison.stat, comparison.denseMean, comparison.denseSD,
+			comparison.sparseMean, comparison.sparseSD, comparison.tStatistic, verdict)
+	}
+	return allAgree
+}
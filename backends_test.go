@@ -0,0 +1,22 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestCompareBackends runs the dense and the sparse backend on the same small board and fails if any stat of comparedStats
+// differs between them, i.e. |t| reaches BackendAgreementThreshold(), see CompareBackends(). the runs are seeded, so the test always gives the same result.
+func TestCompareBackends(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs 40 simulations")
+	}
+	SetMovementTables()
+
+	for _, comparison := range CompareBackends(60, 60, 300, 60, 60, "gardenOfEden", 20, rand.New(rand.NewSource(1))) {
+		if !comparison.agree {
+			t.Errorf("%s differs between the backends: dense %.2f (sd %.2f), sparse %.2f (sd %.2f), t = %.2f",
+				comparison.stat, comparison.denseMean, comparison.denseSD, comparison.sparseMean, comparison.sparseSD, comparison.tStatistic)
+		}
+	}
+}
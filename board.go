@@ -0,0 +1,121 @@
+package main
+
+// Board is what the rules of a generation see of the cells they work on. UpdatePrey(), UpdatePredator() and everything they call
+// (movement, hunting, perception, flocking, feeding and reproduction) only go through a Board, so every storage layout runs the same rules:
+// the dense Ecosystem, which keeps a Unit for every cell, and the SparseGeneration of the sparse backend, which only keeps the occupied cells.
+// Cells are given by their indices i, j, and the rules wrap them around the edges with GetIndex() before asking.
+type Board interface {
+	CountRows() int
+	CountCols() int
+	PreyAt(i, j int) *Prey
+	PredatorAt(i, j int) *Predator
+	SetPrey(i, j int, somePrey *Prey) // nil empties the cell
+	SetPredator(i, j int, shark *Predator)
+	FoodAt(i, j int) Food
+	RemoveFood(i, j int)
+	RefugeAt(i, j int) *Refuge      // nil outside refuges
+	LeaveCarcass(i, j, energy int)  // see DepositCarcass()
+	ThermalFactor(i, j int) float64 // see LocalThermalFactor()
+	Born(i, j int, isPredator bool) // called once a newborn has been placed at i, j
+}
+
+func (someEcosystem *Ecosystem) PreyAt(i, j int) *Prey {
+	return (*someEcosystem)[i][j].prey
+}
+
+func (someEcosystem *Ecosystem) PredatorAt(i, j int) *Predator {
+	return (*someEcosystem)[i][j].predator
+}
+
+func (someEcosystem *Ecosystem) SetPrey(i, j int, somePrey *Prey) {
+	(*someEcosystem)[i][j].prey = somePrey
+}
+
+func (someEcosystem *Ecosystem) SetPredator(i, j int, shark *Predator) {
+	(*someEcosystem)[i][j].predator = shark
+}
+
+func (someEcosystem *Ecosystem) FoodAt(i, j int) Food {
+	return (*someEcosystem)[i][j].food
+}
+
+func (someEcosystem *Ecosystem) RemoveFood(i, j int) {
+	(*someEcosystem)[i][j].food.isPresent = false
+}
+
+func (someEcosystem *Ecosystem) RefugeAt(i, j int) *Refuge {
+	return (*someEcosystem)[i][j].refuge
+}
+
+func (someEcosystem *Ecosystem) LeaveCarcass(i, j, energy int) {
+	DepositCarcass((*someEcosystem)[i][j], energy)
+}
+
+func (someEcosystem *Ecosystem) ThermalFactor(i, j int) float64 {
+	return LocalThermalFactor((*someEcosystem)[i][j])
+}
+
+// Born() does nothing for an Ecosystem: UpdateUnits() visits every Unit anyway, so the newborn is updated in this generation if its Unit hasn't had its turn yet.
+func (someEcosystem *Ecosystem) Born(i, j int, isPredator bool) {}
+
+// Site is where an organism is while UpdatePrey() or UpdatePredator() runs its life cycle. the life cycle only reaches the ocean through its Site,
+// so the engines on a Board (through a LatticeSite) and the continuous engine (through an AgentSite) age, reproduce, move and die by the same rules,
+// and only differ in how an organism is placed, moved and removed.
+type Site interface {
+	ThermalFactor() float64  // see LocalThermalFactor()
+	Remove()                 // takes the organism away once it has died
+	LeaveCarcass(energy int) // see DepositCarcass()
+	Breed() *Organism        // places a newborn of the organism next to it if there is room, see BreedPrey(). Output: the mate, or nil
+	Step() bool              // moves the organism one step, feeding on the way. Output: whether it moved and may take another step
+}
+
+// LatticeSite is the Site of the prey, or with isPredator the predator, in Unit i, j of a Board. Step() keeps i, j up to date.
+type LatticeSite struct {
+	board      Board
+	i, j       int
+	isPredator bool
+}
+
+func (site *LatticeSite) ThermalFactor() float64 {
+	return site.board.ThermalFactor(site.i, site.j)
+}
+
+func (site *LatticeSite) Remove() {
+	if site.isPredator {
+		site.board.SetPredator(site.i, site.j, nil)
+	} else {
+		site.board.SetPrey(site.i, site.j, nil)
+	}
+}
+
+func (site *LatticeSite) LeaveCarcass(energy int) {
+	site.board.LeaveCarcass(site.i, site.j, energy)
+}
+
+func (site *LatticeSite) Breed() *Organism {
+	if site.isPredator {
+		if mate := site.board.PredatorAt(site.i, site.j).Breed(site.board, site.i, site.j); mate != nil {
+			return &mate.Organism
+		}
+		return nil
+	}
+	if mate := BreedPrey(site.board, site.board.PreyAt(site.i, site.j), site.i, site.j); mate != nil {
+		return &mate.Organism
+	}
+	return nil
+}
+
+// Step() of a prey stops once it dies or stays put, and of a predator once it stays put or runs out of energy, see MovePrey() and MovePredator().
+func (site *LatticeSite) Step() bool {
+	if site.isPredator {
+		shark := site.board.PredatorAt(site.i, site.j)
+		newR, newC, isMoving := shark.MovePredator(site.board, site.i, site.j)
+		site.i, site.j = newR, newC
+		return isMoving && shark.energy > 0
+	}
+	currentPrey := site.board.PreyAt(site.i, site.j)
+	newI, newJ := MovePrey(site.board, site.i, site.j)
+	isMoving := newI != site.i || newJ != site.j
+	site.i, site.j = newI, newJ
+	return isMoving && site.board.PreyAt(newI, newJ) == currentPrey
+}
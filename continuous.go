@@ -0,0 +1,785 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Position is a point of a ContinuousOcean, measured in Units: Unit i, j covers rows i to i+1 and columns j to j+1.
+type Position struct {
+	row float64
+	col float64
+}
+
+// Agent is a prey or predator of a ContinuousOcean. exactly one of prey and predator is set, like in a Unit of an Ecosystem.
+type Agent struct {
+	prey     *Prey
+	predator *Predator
+	position Position
+	heading  float64 // in radians, 0 along the columns
+	dead     bool    // eaten or died during the current generation
+}
+
+// ContinuousOcean is the off-lattice engine. the organisms have float positions and headings instead of living in Units, and the space wraps around
+// like an Ecosystem does. the food still lives on the numRows x numCols grid of Units, kept as a Bitset like in a SparseEcosystem.
+// every step an organism turns by its chosen gene index times 45 degrees plus Gaussian noise of turnNoise radians, and swims stepLength Units:
+// the genome biases the turning angle, where gene 0 keeps the heading and gene 4 turns back. a SpatialIndex finds the organisms within
+// captureRadius (predation), interactionRadius (mates, newborns and schooling), exclusionRadius (crowding) or the vision radius, and prey eat the food of a Unit
+// whose centre is within feedingRadius. otherwise UpdateContinuousOcean() runs UpdatePrey() and UpdatePredator() through an AgentSite, so the life cycle
+// is the one of UpdateEcosystem(). FinishContinuousStats() fills in the same stats as FinishStats(),
+// and ToEcosystem() rasterises it for the GIF.
+type ContinuousOcean struct {
+	numRows int
+	numCols int
+	agents  []*Agent
+	food    Bitset
+}
+
+// Body() returns the Organism of agent.
+func (agent *Agent) Body() *Organism {
+	if agent.prey != nil {
+		return &agent.prey.Organism
+	}
+	return &agent.predator.Organism
+}
+
+// DeepCopy() copies someOcean with copies of all its organisms.
+func (someOcean *ContinuousOcean) DeepCopy() *ContinuousOcean {
+	newOcean := &ContinuousOcean{
+		numRows: someOcean.numRows,
+		numCols: someOcean.numCols,
+		agents:  make([]*Agent, len(someOcean.agents)),
+		food:    make(Bitset, len(someOcean.food)),
+	}
+	for k, agent := range someOcean.agents {
+		newAgent := *agent
+		if agent.prey != nil {
+			newAgent.prey = agent.prey.DeepCopyOrganism()
+		} else {
+			newAgent.predator = agent.predator.DeepCopyOrganism()
+		}
+		newOcean.agents[k] = &newAgent
+	}
+	copy(newOcean.food, someOcean.food)
+	return newOcean
+}
+
+// SpeciesEnergy() returns the total energy held by the prey and by the predators, like SpeciesEnergy() of an Ecosystem.
+func (someOcean *ContinuousOcean) SpeciesEnergy() (int, int) {
+	preyEnergy, predEnergy := 0, 0
+	for _, agent := range someOcean.agents {
+		if agent.prey != nil {
+			preyEnergy += agent.prey.energy
+		} else {
+			predEnergy += agent.predator.energy
+		}
+	}
+	return preyEnergy, predEnergy
+}
+
+// UnitOf() returns the indices of the Unit that contains position.
+func (someOcean *ContinuousOcean) UnitOf(position Position) (int, int) {
+	return int(position.row) % someOcean.numRows, int(position.col) % someOcean.numCols
+}
+
+// Wrap() moves position back into the ocean when it has swum over an edge.
+func (someOcean *ContinuousOcean) Wrap(position Position) Position {
+	return Position{row: WrapCoordinate(position.row, someOcean.numRows), col: WrapCoordinate(position.col, someOcean.numCols)}
+}
+
+// WrapCoordinate() returns x modulo boundary, between 0 and boundary.
+func WrapCoordinate(x float64, boundary int) float64 {
+	x -= float64(boundary) * math.Floor(x/float64(boundary))
+	if x >= float64(boundary) {
+		x = 0
+	}
+	return x
+}
+
+// Displacement() is the shortest (row, col) vector from one position to another, going around the edges where that is shorter.
+func (someOcean *ContinuousOcean) Displacement(from, to Position) (float64, float64) {
+	return WrapDelta(to.row-from.row, someOcean.numRows), WrapDelta(to.col-from.col, someOcean.numCols)
+}
+
+// WrapDelta() turns a difference of coordinates into the shortest one on a ring of length boundary.
+func WrapDelta(delta float64, boundary int) float64 {
+	half := float64(boundary) / 2
+	delta = math.Mod(delta, float64(boundary))
+	if delta >= half {
+		delta -= float64(boundary)
+	} else if delta < -half {
+		delta += float64(boundary)
+	}
+	return delta
+}
+
+// HeadingOfDirection() is the heading of a lattice direction, see deltas.
+func HeadingOfDirection(direction int) float64 {
+	return math.Atan2(float64(deltas[direction].row), float64(deltas[direction].col))
+}
+
+// DirectionOfHeading() is the lattice direction closest to heading. it keeps lastDirection meaningful for UpdateDirection() and the mutation operators.
+// heading is rounded to the nearest multiple of 45 degrees first, so every direction gets an equal 45 degree bin, and the deltas of that octant are looked up in deltas.
+func DirectionOfHeading(heading float64) int {
+	octant := int(math.Round(heading/(math.Pi/4))) % 8
+	if octant < 0 {
+		octant += 8
+	}
+	angle := float64(octant) * math.Pi / 4
+	return DirectionOfDelta(int(math.Round(math.Sin(angle))), int(math.Round(math.Cos(angle))))
+}
+
+// ToEcosystem() rasterises someOcean into an Ecosystem for drawing: every organism is put in the Unit that contains it.
+// a Unit can only show one prey and one predator, so crowded Units hide some organisms. the stats come from FinishContinuousStats() instead.
+func (someOcean *ContinuousOcean) ToEcosystem() *Ecosystem {
+	newEco := make(Ecosystem, someOcean.numRows)
+	for i := range newEco {
+		newEco[i] = make([]*Unit, someOcean.numCols)
+		for j := range newEco[i] {
+			newEco[i][j] = new(Unit)
+			newEco[i][j].food.isPresent = someOcean.food.Get(i*someOcean.numCols + j)
+		}
+	}
+	for _, agent := range someOcean.agents {
+		i, j := someOcean.UnitOf(agent.position)
+		if agent.prey != nil {
+			newEco[i][j].prey = agent.prey
+		} else {
+			newEco[i][j].predator = agent.predator
+		}
+	}
+	return &newEco
+}
+
+// SpatialIndex buckets the Agents of a ContinuousOcean by the Unit they are in, so the Agents near a point can be found
+// without looking at all of them.
+type SpatialIndex struct {
+	ocean   *ContinuousOcean
+	buckets map[int][]*Agent
+}
+
+// NewSpatialIndex() makes an empty SpatialIndex of someOcean.
+func NewSpatialIndex(someOcean *ContinuousOcean) *SpatialIndex {
+	return &SpatialIndex{ocean: someOcean, buckets: make(map[int][]*Agent)}
+}
+
+func (index *SpatialIndex) bucket(position Position) int {
+	i, j := index.ocean.UnitOf(position)
+	return i*index.ocean.numCols + j
+}
+
+// Insert() adds agent at its position.
+func (index *SpatialIndex) Insert(agent *Agent) {
+	key := index.bucket(agent.position)
+	index.buckets[key] = append(index.buckets[key], agent)
+}
+
+// Remove() takes agent out of the index. it must still be at the position it was inserted at.
+func (index *SpatialIndex) Remove(agent *Agent) {
+	key := index.bucket(agent.position)
+	bucket := index.buckets[key]
+	for k := range bucket {
+		if bucket[k] == agent {
+			bucket[k] = bucket[len(bucket)-1]
+			bucket = bucket[:len(bucket)-1]
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(index.buckets, key)
+	} else {
+		index.buckets[key] = bucket
+	}
+}
+
+// Move() moves agent to newPosition.
+func (index *SpatialIndex) Move(agent *Agent, newPosition Position) {
+	index.Remove(agent)
+	agent.position = newPosition
+	index.Insert(agent)
+}
+
+// Near() calls visit for every Agent within radius of position, with the displacement from position to it and its distance.
+func (index *SpatialIndex) Near(position Position, radius float64, visit func(agent *Agent, deltaRow, deltaCol, distance float64)) {
+	numRows, numCols := index.ocean.numRows, index.ocean.numCols
+	i, j := index.ocean.UnitOf(position)
+	reach := int(math.Ceil(radius))
+	// on a small ocean the buckets within reach wrap around onto each other, so each is only looked at once
+	seen := SeenCells(reach, numRows, numCols)
+	for deltaRow := -reach; deltaRow <= reach; deltaRow++ {
+		for deltaCol := -reach; deltaCol <= reach; deltaCol++ {
+			key := GetIndex(i, deltaRow, numRows)*numCols + GetIndex(j, deltaCol, numCols)
+			if seen != nil {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			for _, agent := range index.buckets[key] {
+				agentRow, agentCol := index.ocean.Displacement(position, agent.position)
+				distance := math.Hypot(agentRow, agentCol)
+				if distance <= radius {
+					visit(agent, agentRow, agentCol, distance)
+				}
+			}
+		}
+	}
+}
+
+// SeenCells() returns a map to remember the cells already looked at when looking reach cells around a point wraps onto itself
+// on a numRows x numCols board, and nil when it can't.
+func SeenCells(reach, numRows, numCols int) map[int]bool {
+	if 2*reach+1 > numRows || 2*reach+1 > numCols {
+		return make(map[int]bool)
+	}
+	return nil
+}
+
+// InitializeContinuousOcean() is InitializeEcosystem() for the continuous engine: food in 10% of the Units, and the prey and predators at random positions.
+func InitializeContinuousOcean(numRows, numCols, numPrey, numPred int) *ContinuousOcean {
+	CheckSimpleModel("continuous simulationEngine", false)
+
+	newOcean := &ContinuousOcean{numRows: numRows, numCols: numCols, food: NewBitset(numRows * numCols)}
+	SampleCells(numRows*numCols, 0.10, func(cell int) {
+		newOcean.food.Set(cell, true)
+	})
+
+	for k := 0; k < numPred; k++ {
+		newOcean.AddAgent(&Agent{predator: CreatePredator()})
+	}
+	for k := 0; k < numPrey; k++ {
+		newOcean.AddAgent(&Agent{prey: CreatePrey()})
+	}
+	return newOcean
+}
+
+// AddAgent() places a founder at a random position, heading in its lastDirection.
+func (someOcean *ContinuousOcean) AddAgent(agent *Agent) {
+	agent.position = Position{row: rng.Float64() * float64(someOcean.numRows), col: rng.Float64() * float64(someOcean.numCols)}
+	agent.heading = HeadingOfDirection(agent.Body().lastDirection)
+	someOcean.agents = append(someOcean.agents, agent)
+}
+
+// SimulateContinuousOcean() is SimulateEcosystemEvolution() for the continuous engine. like SimulateSparseEcosystemEvolution() it only returns
+// generation 0, every keepEvery-th generation and the last one. the stats of every generation go to allStats
+func SimulateContinuousOcean(initialOcean *ContinuousOcean, totalTimesteps int, foodRule string, keepEvery int) []*ContinuousOcean {
+	fmt.Println("SimulateContinuousOcean is running")
+
+	ResetStats(0)
+	curStats.preyLedger.introduced, curStats.predLedger.introduced = initialOcean.SpeciesEnergy()
+	allStats = []GenerationStats{FinishContinuousStats(initialOcean)}
+
+	kept := []*ContinuousOcean{initialOcean}
+	curOcean := initialOcean
+	for i := 1; i <= totalTimesteps; i++ {
+		curOcean = UpdateContinuousOcean(curOcean, foodRule, i)
+		allStats = append(allStats, FinishContinuousStats(curOcean))
+		if i%keepEvery == 0 || i == totalTimesteps {
+			kept = append(kept, curOcean)
+		}
+
+		if (totalTimesteps/10) != 0 && i%(totalTimesteps/10) == 0 {
+			fmt.Println("Simulation is", 100*float64(i)/float64(totalTimesteps), "percent complete. Generation =", i)
+		}
+	}
+	return kept
+}
+
+// agentVisit is the time during a generation at which an Agent is updated, or, with a nil agent, food grows in cell. see ContinuousGeneration
+type agentVisit struct {
+	time  float64
+	agent *Agent
+	cell  int
+}
+
+// agentQueue is a min-heap of agentVisits by time, for container/heap.
+type agentQueue []agentVisit
+
+func (queue agentQueue) Len() int            { return len(queue) }
+func (queue agentQueue) Less(a, b int) bool  { return queue[a].time < queue[b].time }
+func (queue agentQueue) Swap(a, b int)       { queue[a], queue[b] = queue[b], queue[a] }
+func (queue *agentQueue) Push(x interface{}) { *queue = append(*queue, x.(agentVisit)) }
+func (queue *agentQueue) Pop() interface{} {
+	old := *queue
+	last := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return last
+}
+
+// ContinuousGeneration is the state of one call of UpdateContinuousOcean(). like a SparseGeneration, every Agent and every cell where food will grow
+// gets a random visit time between 0 and 1, and they are visited in order of time. a newborn gets its own time, and is only visited if that time hasn't passed.
+type ContinuousGeneration struct {
+	ocean     *ContinuousOcean
+	curGen    int
+	now       float64
+	visits    agentQueue
+	preyIndex *SpatialIndex
+	predIndex *SpatialIndex
+}
+
+// UpdateContinuousOcean() is UpdateEcosystem() for the continuous engine.
+func UpdateContinuousOcean(prevOcean *ContinuousOcean, foodRule string, curGen int) *ContinuousOcean {
+	ResetStats(curGen)
+	nextOcean := prevOcean.DeepCopy()
+	curStats.preyLedger.startEnergy, curStats.predLedger.startEnergy = nextOcean.SpeciesEnergy()
+
+	gen := &ContinuousGeneration{
+		ocean:     nextOcean,
+		curGen:    curGen,
+		now:       -1,
+		preyIndex: NewSpatialIndex(nextOcean),
+		predIndex: NewSpatialIndex(nextOcean),
+	}
+	for _, agent := range nextOcean.agents {
+		gen.Index(agent).Insert(agent)
+		gen.Schedule(agentVisit{agent: agent})
+	}
+	SampleFoodCells(nextOcean.numRows, nextOcean.numCols, foodRule, curGen, func(cell int) {
+		gen.Schedule(agentVisit{cell: cell})
+	})
+
+	for gen.visits.Len() > 0 {
+		nextVisit := heap.Pop(&gen.visits).(agentVisit)
+		gen.now = nextVisit.time
+		gen.Visit(nextVisit)
+	}
+
+	// the newborns were appended to the agents, and the dead are dropped
+	var survivors []*Agent
+	for _, agent := range nextOcean.agents {
+		if !agent.dead {
+			survivors = append(survivors, agent)
+		}
+	}
+	nextOcean.agents = survivors
+
+	return nextOcean
+}
+
+// Index() is the SpatialIndex of the species of agent.
+func (gen *ContinuousGeneration) Index(agent *Agent) *SpatialIndex {
+	if agent.prey != nil {
+		return gen.preyIndex
+	}
+	return gen.predIndex
+}
+
+// Schedule() gives someVisit a random time, and queues it if that time hasn't passed.
+func (gen *ContinuousGeneration) Schedule(someVisit agentVisit) {
+	someVisit.time = rng.Float64()
+	if someVisit.time > gen.now {
+		heap.Push(&gen.visits, someVisit)
+	}
+}
+
+// Visit() updates the Agent of someVisit with UpdatePrey() or UpdatePredator(), or grows food in its cell.
+func (gen *ContinuousGeneration) Visit(someVisit agentVisit) {
+	agent := someVisit.agent
+	if agent == nil {
+		gen.ocean.food.Set(someVisit.cell, true)
+	} else if !agent.dead && agent.Body().lastGenUpdated != gen.curGen {
+		site := &AgentSite{gen: gen, agent: agent}
+		if agent.prey != nil {
+			UpdatePrey(site, agent.prey, gen.curGen)
+		} else {
+			agent.predator.UpdatePredator(site, gen.curGen)
+		}
+	}
+}
+
+// AgentSite is the Site of an Agent of a ContinuousGeneration. decomposition isn't supported (see CheckSimpleModel()), so no carcass is left.
+type AgentSite struct {
+	gen   *ContinuousGeneration
+	agent *Agent
+}
+
+func (site *AgentSite) ThermalFactor() float64 {
+	return site.gen.ThermalFactor(site.agent.position)
+}
+
+func (site *AgentSite) Remove() {
+	site.gen.Kill(site.agent)
+}
+
+func (site *AgentSite) LeaveCarcass(energy int) {}
+
+// Breed() is BreedPrey() and Breed() for the continuous engine: the newborn needs an uncrowded point within interactionRadius (see NewbornPosition()),
+// and in sexual mode a mate within interactionRadius (see FindMate()).
+func (site *AgentSite) Breed() *Organism {
+	gen, agent := site.gen, site.agent
+	var babyPrey Prey
+	var babyShark Predator
+	newborn := &Agent{prey: &babyPrey}
+	isSexual := IsSexual(reproductionModePrey)
+	if agent.predator != nil {
+		newborn = &Agent{predator: &babyShark}
+		isSexual = IsSexual(reproductionModePredator)
+	}
+
+	position, found := gen.NewbornPosition(agent, newborn)
+	var mate *Agent
+	if isSexual {
+		mate = gen.FindMate(agent)
+	}
+	if !found || (mate == nil && isSexual) {
+		return nil
+	}
+
+	if agent.prey != nil && mate != nil {
+		ReproducePreySexually(agent.prey, mate.prey, &babyPrey)
+	} else if agent.prey != nil {
+		ReproducePrey(agent.prey, &babyPrey)
+	} else if mate != nil {
+		agent.predator.ReproduceSexually(mate.predator, &babyShark)
+	} else {
+		agent.predator.Reproduce(&babyShark)
+	}
+	gen.Bear(newborn, position)
+
+	if mate == nil {
+		return nil
+	}
+	return mate.Body()
+}
+
+// Step() is MovePrey() and MovePredator() for the continuous engine: the agent swims (see Swim()) and pays for it, then a prey eats
+// the nearest food within feedingRadius and a predator catches the nearest prey within captureRadius (see Catch()).
+// a prey that can't swim still eats where it is, and one that runs out of energy on the way starves. a predator that runs out of energy doesn't eat.
+func (site *AgentSite) Step() bool {
+	gen, agent := site.gen, site.agent
+	if agent.predator != nil {
+		shark := agent.predator
+		geneIndex, isMoving := gen.Swim(agent, GenomeWeights(shark.genome))
+		if isMoving {
+			shark.DecreaseEnergy(geneIndex, StochasticRound(stepLength))
+		}
+		if shark.energy <= 0 {
+			return false
+		}
+		gen.Catch(agent)
+		return isMoving
+	}
+
+	currentPrey := agent.prey
+	geneIndex, isMoving := gen.Swim(agent, gen.PreyTurnWeights(agent))
+	if isMoving {
+		currentPrey.DecreaseEnergy(geneIndex, StochasticRound(stepLength))
+	}
+	if currentPrey.energy <= 0 {
+		StarvePrey(site, currentPrey)
+		return false
+	}
+
+	cell := gen.NearestFood(agent.position, feedingRadius)
+	someFood := Food{isPresent: cell != -1}
+	if CheckIfEats(someFood, currentPrey) {
+		currentPrey.FeedOrganism(someFood)
+		gen.ocean.food.Set(cell, false)
+	}
+	return isMoving
+}
+
+// Kill() removes agent from the ocean.
+func (gen *ContinuousGeneration) Kill(agent *Agent) {
+	agent.dead = true
+	gen.Index(agent).Remove(agent)
+}
+
+// IsCrowded() says whether agent would be within exclusionRadius of another organism at position. it is the continuous version of a Unit
+// that is taken (see isFreeUnit() and IsItAvailable()): a prey keeps away from the other prey and from the predators, and a predator keeps away
+// from the other predators, and from the prey unless it can eat them. an exclusionRadius of 0 lets the organisms overlap.
+func (gen *ContinuousGeneration) IsCrowded(agent *Agent, position Position) bool {
+	if exclusionRadius <= 0 {
+		return false
+	}
+
+	isCrowded := false
+	visit := func(neighbour *Agent, deltaRow, deltaCol, distance float64) {
+		if neighbour != agent {
+			isCrowded = true
+		}
+	}
+	gen.Index(agent).Near(position, exclusionRadius, visit)
+	if agent.prey != nil {
+		gen.predIndex.Near(position, exclusionRadius, visit)
+	} else if !agent.predator.CanEat() {
+		gen.preyIndex.Near(position, exclusionRadius, visit)
+	}
+	return isCrowded
+}
+
+// NewbornPosition() looks for a point within interactionRadius of parent where newborn isn't crowded (see IsCrowded()). like TryTurns() it gives up
+// after 20 random points, and then there is no room for a newborn, as when no neighbour of a Unit is free.
+// Output: the point, and whether one was found
+func (gen *ContinuousGeneration) NewbornPosition(parent, newborn *Agent) (Position, bool) {
+	for numTries := 0; numTries < 20; numTries++ {
+		distance := interactionRadius * math.Sqrt(rng.Float64())
+		angle := 2 * math.Pi * rng.Float64()
+		position := gen.ocean.Wrap(Position{row: parent.position.row + distance*math.Sin(angle), col: parent.position.col + distance*math.Cos(angle)})
+		if !gen.IsCrowded(newborn, position) {
+			return position, true
+		}
+	}
+	return Position{}, false
+}
+
+// Bear() places newborn at position, heading in its lastDirection.
+func (gen *ContinuousGeneration) Bear(newborn *Agent, position Position) {
+	newborn.position = position
+	newborn.heading = HeadingOfDirection(newborn.Body().lastDirection)
+
+	gen.ocean.agents = append(gen.ocean.agents, newborn)
+	gen.Index(newborn).Insert(newborn)
+	gen.Schedule(agentVisit{agent: newborn})
+}
+
+// ThermalFactor() is UnitThermalFactor() of the Unit that contains position.
+func (gen *ContinuousGeneration) ThermalFactor(position Position) float64 {
+	i, j := gen.ocean.UnitOf(position)
+	return UnitThermalFactor(i, j, gen.ocean.numRows, gen.curGen)
+}
+
+// Swim() turns agent by a gene index chosen with weights (times 45 degrees, plus noise) and moves it stepLength Units along its new heading.
+// like TryTurns() it tries up to 20 turns, and the agent stays where it is if every one of them would crowd it, see IsCrowded().
+// Output: the gene index of the turn, and whether the agent moved
+func (gen *ContinuousGeneration) Swim(agent *Agent, weights [8]float64) (int, bool) {
+	for numTries := 0; numTries < 20; numTries++ {
+		geneIndex := ChooseGeneIndex(weights)
+		heading := agent.heading + float64(geneIndex)*math.Pi/4 + turnNoise*rng.NormFloat64()
+		newPosition := gen.ocean.Wrap(Position{row: agent.position.row + stepLength*math.Sin(heading), col: agent.position.col + stepLength*math.Cos(heading)})
+		if !gen.IsCrowded(agent, newPosition) {
+			agent.heading = heading
+			agent.Body().lastDirection = DirectionOfHeading(heading)
+			gen.Index(agent).Move(agent, newPosition)
+			return geneIndex, true
+		}
+	}
+	return 0, false
+}
+
+// PreyTurnWeights() is PreyDirectionWeights() for the continuous engine: ApplyPulls() with the food and the predators within the vision radius,
+// for the heading after every turn (see HeadingVectors()).
+func (gen *ContinuousGeneration) PreyTurnWeights(agent *Agent) [8]float64 {
+	currentPrey := agent.prey
+	weights := GenomeWeights(currentPrey.genome)
+	radius := float64(currentPrey.traits.visionRadius)
+	if radius <= 0 {
+		return weights
+	}
+
+	var foodPull, predatorPull [2]float64
+	gen.FoodNear(agent.position, radius, func(cell int, deltaRow, deltaCol, distance float64) {
+		efficiency := DietEfficiency(currentPrey.traits.diet, 0)
+		pullRow, pullCol := PullTowards(deltaRow, deltaCol)
+		foodPull[0] += efficiency * pullRow
+		foodPull[1] += efficiency * pullCol
+	})
+	gen.predIndex.Near(agent.position, radius, func(shark *Agent, deltaRow, deltaCol, distance float64) {
+		if distance > 0 {
+			pullRow, pullCol := PullTowards(deltaRow, deltaCol)
+			predatorPull[0] += pullRow
+			predatorPull[1] += pullCol
+		}
+	})
+
+	// flocking isn't supported off the lattice, see CheckSimpleModel()
+	var heading, centre, crowding [2]float64
+	return ApplyPulls(weights, currentPrey, HeadingVectors(agent.heading), foodPull, predatorPull, heading, centre, crowding)
+}
+
+// HeadingVectors() is the unit vector of the heading every gene index turns to from heading, leaving out the noise.
+func HeadingVectors(heading float64) [8][2]float64 {
+	var turns [8][2]float64
+	for idx := range turns {
+		turnedHeading := heading + float64(idx)*math.Pi/4
+		turns[idx] = [2]float64{math.Sin(turnedHeading), math.Cos(turnedHeading)}
+	}
+	return turns
+}
+
+// FoodNear() calls visit for every Unit with food whose centre is within radius of position (and not at it), with the displacement to the centre and its distance.
+func (gen *ContinuousGeneration) FoodNear(position Position, radius float64, visit func(cell int, deltaRow, deltaCol, distance float64)) {
+	numRows, numCols := gen.ocean.numRows, gen.ocean.numCols
+	i, j := gen.ocean.UnitOf(position)
+	reach := int(math.Ceil(radius))
+	seen := SeenCells(reach, numRows, numCols)
+	for deltaRow := -reach; deltaRow <= reach; deltaRow++ {
+		for deltaCol := -reach; deltaCol <= reach; deltaCol++ {
+			row, col := GetIndex(i, deltaRow, numRows), GetIndex(j, deltaCol, numCols)
+			cell := row*numCols + col
+			if !gen.ocean.food.Get(cell) || seen[cell] {
+				continue
+			}
+			if seen != nil {
+				seen[cell] = true
+			}
+			centreRow, centreCol := gen.ocean.Displacement(position, Position{row: float64(row) + 0.5, col: float64(col) + 0.5})
+			if distance := math.Hypot(centreRow, centreCol); distance <= radius && distance > 0 {
+				visit(cell, centreRow, centreCol, distance)
+			}
+		}
+	}
+}
+
+// NearestFood() is the cell with food whose centre is closest to position, within radius, or -1 if there is none.
+func (gen *ContinuousGeneration) NearestFood(position Position, radius float64) int {
+	nearest, nearestDistance := -1, math.Inf(1)
+	i, j := gen.ocean.UnitOf(position)
+	if cell := i*gen.ocean.numCols + j; gen.ocean.food.Get(cell) {
+		// the food of the Unit the prey is in is always in reach
+		nearest, nearestDistance = cell, 0
+	}
+	gen.FoodNear(position, radius, func(cell int, deltaRow, deltaCol, distance float64) {
+		if distance < nearestDistance {
+			nearest, nearestDistance = cell, distance
+		}
+	})
+	return nearest
+}
+
+// FindMate() looks within interactionRadius of agent for another organism of its species that is also mature (see IsMature()), like FindMatePrey().
+// Output: a randomly chosen eligible mate, or nil if there is none
+func (gen *ContinuousGeneration) FindMate(agent *Agent) *Agent {
+	var mates []*Agent
+	gen.Index(agent).Near(agent.position, interactionRadius, func(neighbour *Agent, deltaRow, deltaCol, distance float64) {
+		if neighbour != agent && neighbour.Body().IsMature(gen.ThermalFactor(neighbour.position)) {
+			mates = append(mates, neighbour)
+		}
+	})
+	if len(mates) == 0 {
+		return nil
+	}
+	return mates[rng.Intn(len(mates))]
+}
+
+// Catch() lets the predator agent eat the nearest prey within captureRadius, like FeedShark().
+func (gen *ContinuousGeneration) Catch(agent *Agent) {
+	shark := agent.predator
+	if !shark.CanEat() {
+		return
+	}
+
+	var caught *Agent
+	nearestDistance := math.Inf(1)
+	gen.preyIndex.Near(agent.position, captureRadius, func(preyAgent *Agent, deltaRow, deltaCol, distance float64) {
+		if distance < nearestDistance {
+			caught, nearestDistance = preyAgent, distance
+		}
+	})
+	if caught == nil {
+		return
+	}
+
+	curStats.preyEaten++
+	if CountNeighbours(gen.preyIndex, caught) > 0 {
+		curStats.schooledPreyEaten++
+	}
+	curStats.preyLedger.lostAtDeath += caught.prey.energy
+	gen.Kill(caught)
+	shark.IncreaseEngeryAfterMeal(caught.prey.energy)
+	shark.StartHandling()
+}
+
+// CountNeighbours() counts the other Agents of index within interactionRadius of agent, the continuous version of the 8 neighbours of a Unit.
+func CountNeighbours(index *SpatialIndex, agent *Agent) int {
+	count := 0
+	index.Near(agent.position, interactionRadius, func(neighbour *Agent, deltaRow, deltaCol, distance float64) {
+		if neighbour != agent {
+			count++
+		}
+	})
+	return count
+}
+
+// FinishContinuousStats() is FinishStats() for the continuous engine. a prey is schooled if another prey is within interactionRadius,
+// and preyClustering compares the prey within interactionRadius of each prey with a random scatter. meanTemperature is left at 0
+func FinishContinuousStats(someOcean *ContinuousOcean) GenerationStats {
+	stats := curStats
+	stats.meanPreyTraits = make([]float64, len(traitNames))
+	stats.meanPredTraits = make([]float64, len(traitNames))
+	stats.meanPreyDiet = make([]float64, NumFoodTypes())
+
+	preyIndex := NewSpatialIndex(someOcean)
+	for _, agent := range someOcean.agents {
+		if agent.prey != nil {
+			preyIndex.Insert(agent)
+		}
+	}
+
+	totalNeighbours := 0
+	for _, agent := range someOcean.agents {
+		someOrganism := agent.Body()
+		if someOrganism.infection == susceptible {
+			stats.numSusceptible++
+		} else if someOrganism.infection == infected {
+			stats.numInfected++
+		} else {
+			stats.numRecovered++
+		}
+
+		row, _ := someOcean.UnitOf(agent.position)
+		if agent.predator != nil {
+			stats.numPred++
+			stats.meanPredRow += float64(row)
+			if !agent.predator.CanEat() {
+				stats.numHandlingPred++
+			}
+			AddTraitValues(stats.meanPredTraits, agent.predator.traits)
+			continue
+		}
+
+		stats.numPrey++
+		stats.meanPreyRow += float64(row)
+		AddTraitValues(stats.meanPreyTraits, agent.prey.traits)
+		for foodType, preference := range agent.prey.traits.diet {
+			stats.meanPreyDiet[foodType] += preference
+		}
+		stats.dietSpecialisation += DietSpecialisation(agent.prey.traits.diet)
+		if neighbours := CountNeighbours(preyIndex, agent); neighbours > 0 {
+			stats.numSchooledPrey++
+			totalNeighbours += neighbours
+		}
+	}
+
+	area := float64(someOcean.numRows * someOcean.numCols)
+	if stats.numPrey >= 2 {
+		expected := float64(stats.numPrey-1) * math.Pi * interactionRadius * interactionRadius / area
+		stats.preyClustering = float64(totalNeighbours) / float64(stats.numPrey) / expected
+	}
+
+	stats.numFood = someOcean.food.Count()
+	_, heldEnergy := FoodEnergy(0)
+	stats.preyLedger.endEnergy, stats.predLedger.endEnergy = someOcean.SpeciesEnergy()
+	stats.organismEnergy = stats.preyLedger.endEnergy + stats.predLedger.endEnergy
+	stats.foodEnergy = stats.numFood * heldEnergy
+	CheckLedgers(stats)
+
+	AverageStats(&stats, someOcean.numRows*someOcean.numCols)
+
+	return stats
+}
+
+// WriteGenomesToFile() writes the genomes of the organisms of someOcean to filename, in the format of WriteGenomesToFile().
+func (someOcean *ContinuousOcean) WriteGenomesToFile(filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create genome file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, agent := range someOcean.agents {
+		if agent.prey != nil {
+			WriteGenomeLine(writer, "prey", &agent.prey.Organism)
+		} else {
+			WriteGenomeLine(writer, "predator", &agent.predator.Organism)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		panic("could not write genome file " + filename + ": " + err.Error())
+	}
+}
@@ -3,6 +3,9 @@ package main
 // 2D array of Unit objects
 type Ecosystem [][]*Unit
 
+// the layers of an ocean with depth, surface first. every layer is an Ecosystem of the same size, see depth.go
+type Ocean []*Ecosystem
+
 // OrderedPair
 type OrderedPair struct {
 	row, col int
@@ -13,22 +16,49 @@ type Unit struct {
 	food     Food
 	predator *Predator
 	prey     *Prey
+	detritus Detritus // dead organic matter, see decomposition.go
+	refuge   *Refuge  // the predator-free refuge the Unit belongs to, nil outside refuges. see refuges.go
+
+	temperature float64 // in degrees Celsius, filled in by UpdateEnvironment()
+	light       float64 // filled in by UpdateEnvironment()
 }
 
 type Food struct {
 	isPresent bool
+	foodType  int // index into foodTypes, see FoodType
 	// lastGenUpdated int // if newly made, gets set to current generation. if eaten, gets set to -1. if this is not the current generation, then the Prey can eat it (because it wasn't made during the current generation).
 }
 
 type Organism struct {
 	// we don't need location OrderedPair because we are using an [][]Unit
-	energy         int
-	age            int
-	genome         [8]Gene
-	lastGenUpdated int // gets updated to current generation after the organism has moved (so it doesn't move twice when updating for the next generation)
-	lastDirection  int // a number between 0 and 7, corresponding to which gene was chosen for the last movement
+	energy                int
+	age                   int // generations since birth
+	timeSinceReproduction int // generations since birth or the last reproduction, whichever is later
+	genome                [8]Gene
+	lastGenUpdated        int // gets updated to current generation after the organism has moved (so it doesn't move twice when updating for the next generation)
+	lastDirection         int // a number between 0 and 7, corresponding to which gene was chosen for the last movement
+
+	traits Traits // heritable life-history traits, see traits.go
+
+	verticalGenome VerticalGenome // how the organism moves between depth layers by day and by night, see depth.go
+
+	// only used by predators. a predator that is handling a kill, or whose gut is full, can't eat
+	handlingTime int // generations left handling the last kill
+	gutContents  int // prey eaten and not yet digested
+
+	infection   InfectionState
+	infectedFor int // generations since the organism was infected
 }
 
+// InfectionState is where an organism is in the disease, see disease.go
+type InfectionState int
+
+const (
+	susceptible InfectionState = iota // the zero value, so organisms are born susceptible
+	infected
+	recovered
+)
+
 type Gene float64 // with range 0 to 1. all the genes of a genome add up to 1
 
 type Prey struct {
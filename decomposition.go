@@ -0,0 +1,103 @@
+package main
+
+// Detritus is the dead organic matter lying in a Unit. Carcasses add to energy, which slowly decays into nutrients,
+// and once a Unit holds enough nutrients they grow into a plankton. see UpdateDetritus()
+type Detritus struct {
+	energy    int // energy of the carcasses that haven't decayed yet
+	nutrients int // decayed energy waiting to grow into plankton
+}
+
+// DepositCarcass() leaves the energy of a dead organism in someUnit as detritus. it does nothing when decomposition is off,
+// or when there is no energy left, e.g. for an organism that starved.
+func DepositCarcass(someUnit *Unit, energy int) {
+	if !decompositionEnabled || energy <= 0 {
+		return
+	}
+	someUnit.detritus.energy += energy
+	curStats.carcassEnergy += energy
+}
+
+// UpdateDetritus() runs one generation of decomposition on someEcosystem.
+// A decayRate share of the detritus energy in every Unit becomes nutrients, a nutrientSpread share of the nutrients
+// is split between the 8 neighbours, and every empty Unit with enough nutrients grows a plankton out of them: a random food type that may grow there (see RandomFoodType()),
+// using up the energy it holds.
+// Within a generation the energy only moves between Units, detritus, nutrients and plankton, so none is lost or made here.
+// A plankton grown this way holds its full FoodEnergy(), but the prey that eats it only gets what EnergyTransferred() gives,
+// e.g. energyGainedPerPlankton out of planktonEnergy under the default "fixed" rule. The rest leaves the food web, as it does for any other plankton.
+func UpdateDetritus(someEcosystem *Ecosystem) {
+	numRows := someEcosystem.CountRows()
+	numCols := someEcosystem.CountCols()
+
+	// nutrients arriving from the neighbours, added after every Unit has given its share so the order doesn't matter
+	incoming := make([][]int, numRows)
+	for i := range incoming {
+		incoming[i] = make([]int, numCols)
+	}
+
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curDetritus := &(*someEcosystem)[i][j].detritus
+
+			decayed := int(decayRate * float64(curDetritus.energy))
+			// make sure small deposits finish decaying instead of lingering forever
+			if decayed == 0 && curDetritus.energy > 0 {
+				decayed = 1
+			}
+			curDetritus.energy -= decayed
+			curDetritus.nutrients += decayed
+
+			// spread whole units of energy to each neighbour
+			share := int(nutrientSpread * float64(curDetritus.nutrients) / 8)
+			if share == 0 {
+				continue
+			}
+			for _, moveDeltas := range deltas {
+				incoming[GetIndex(i, moveDeltas.row, numRows)][GetIndex(j, moveDeltas.col, numCols)] += share
+			}
+			curDetritus.nutrients -= 8 * share
+		}
+	}
+
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curUnit := (*someEcosystem)[i][j]
+			curUnit.detritus.nutrients += incoming[i][j]
+
+			if curUnit.food.isPresent {
+				continue
+			}
+			foodType := RandomFoodType(i, j)
+			if foodType == -1 {
+				continue
+			}
+			if _, foodEnergy := FoodEnergy(foodType); curUnit.detritus.nutrients >= foodEnergy {
+				curUnit.detritus.nutrients -= foodEnergy
+				curUnit.food = Food{isPresent: true, foodType: foodType}
+				curStats.planktonFromNutrients++
+			}
+		}
+	}
+}
+
+// TotalEnergy() audits the energy held in someEcosystem: by the organisms, by the plankton (see FoodEnergy()),
+// and by the detritus and nutrients.
+func TotalEnergy(someEcosystem *Ecosystem) (int, int, int) {
+	organismEnergy, foodEnergy, detritusEnergy := 0, 0, 0
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curUnit := (*someEcosystem)[i][j]
+			if curUnit.prey != nil {
+				organismEnergy += curUnit.prey.energy
+			}
+			if curUnit.predator != nil {
+				organismEnergy += curUnit.predator.energy
+			}
+			if curUnit.food.isPresent {
+				_, heldEnergy := FoodEnergy(curUnit.food.foodType)
+				foodEnergy += heldEnergy
+			}
+			detritusEnergy += curUnit.detritus.energy + curUnit.detritus.nutrients
+		}
+	}
+	return organismEnergy, foodEnergy, detritusEnergy
+}
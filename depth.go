@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// VerticalGenome is the part of the genome for moving between depth layers. For day and for night (see DielPhase()) it holds the weights
+// of moving up a layer, staying, and moving down a layer, which add up to 1 like the direction genome. An organism that goes down by day
+// and up by night has evolved diel vertical migration.
+type VerticalGenome [2][3]Gene
+
+// the phases of the day, the first index of a VerticalGenome
+const (
+	day   = 0
+	night = 1
+)
+
+// the vertical moves, the second index of a VerticalGenome
+const (
+	moveUp   = 0
+	stayPut  = 1
+	moveDown = 2
+)
+
+// curLight is the light in the layer that is being updated, from 1 at the surface by day down to almost 0 in the deep or at night, see Light().
+// predators hunt by sight, so it scales how far a hunting predator sees and how often its pounce succeeds. it is always 1 in a flat ocean.
+var curLight float64 = 1
+
+// DielPhase() says whether generation curGen is in the day or the night. the first half of every dayLength generations is day.
+// dayLength can be changed mid-run by an Intervention, so it is checked here rather than once at the start.
+func DielPhase(curGen int) int {
+	if dayLength < 1 {
+		panic("dayLength must be at least 1 generation")
+	}
+	if curGen%dayLength < (dayLength+1)/2 {
+		return day
+	}
+	return night
+}
+
+// DepthLight() is the share of the surface light that reaches layer, exp(-lightAttenuation * layer). food grows in proportion to it.
+func DepthLight(layer int) float64 {
+	return math.Exp(-lightAttenuation * float64(layer))
+}
+
+// Light() is the light in layer in generation curGen: DepthLight() by day, and nightLight times that by night.
+func Light(layer, curGen int) float64 {
+	if DielPhase(curGen) == night {
+		return nightLight * DepthLight(layer)
+	}
+	return DepthLight(layer)
+}
+
+// FounderVerticalGenome() is the vertical genome of the founding population: every move is as likely by day as by night, so there is no migration yet.
+func FounderVerticalGenome() VerticalGenome {
+	var newGenome VerticalGenome
+	for phase := range newGenome {
+		for move := range newGenome[phase] {
+			newGenome[phase][move] = Gene(1.0 / 3)
+		}
+	}
+	return newGenome
+}
+
+// InheritVerticalGenome() returns a child's vertical genome: the average of genome1 and genome2 (pass the parent's genome twice for asexual reproduction),
+// with Gaussian noise of verticalMutationStrength on every weight, renormalised. in a flat ocean the genome isn't used, so it is just copied.
+func InheritVerticalGenome(genome1, genome2 VerticalGenome) VerticalGenome {
+	if numLayers == 1 {
+		return genome1
+	}
+	var childGenome VerticalGenome
+	for phase := range childGenome {
+		for move := range childGenome[phase] {
+			childGenome[phase][move] = (genome1[phase][move]+genome2[phase][move])/2 + Gene(rng.NormFloat64()*verticalMutationStrength)
+		}
+	}
+	return NormalizeVerticalGenome(childGenome)
+}
+
+// NormalizeVerticalGenome() rescales the weights of each phase of someGenome so that they add up to 1, like NormalizeGenome().
+// negative weights become 0, and a phase of all zeros becomes even.
+func NormalizeVerticalGenome(someGenome VerticalGenome) VerticalGenome {
+	for phase := range someGenome {
+		sum := Gene(0)
+		for move := range someGenome[phase] {
+			if someGenome[phase][move] < 0 {
+				someGenome[phase][move] = 0
+			}
+			sum += someGenome[phase][move]
+		}
+		for move := range someGenome[phase] {
+			if sum == 0 {
+				someGenome[phase][move] = Gene(1.0 / 3)
+			} else {
+				someGenome[phase][move] /= sum
+			}
+		}
+	}
+	return someGenome
+}
+
+// ChooseVerticalMove() picks a vertical move with the weights of one phase of a VerticalGenome.
+// Output: the change in layer, -1 for up, 0 for staying and 1 for down
+func ChooseVerticalMove(weights [3]Gene) int {
+	randomNumber := Gene(rng.Float64())
+	if randomNumber < weights[moveUp] {
+		return -1
+	} else if randomNumber < weights[moveUp]+weights[stayPut] {
+		return 0
+	}
+	return 1
+}
+
+// DielMigration() is how much further down genome moves by day than by night: the expected move (down minus up) by day minus the expected move by night.
+// it is 0 without migration and up to 2 for an organism that always dives by day and always rises at night.
+func DielMigration(genome VerticalGenome) float64 {
+	dayMove := genome[day][moveDown] - genome[day][moveUp]
+	nightMove := genome[night][moveDown] - genome[night][moveUp]
+	return float64(dayMove - nightMove)
+}
+
+// InitializeOcean() makes an Ocean of numLayers layers of numRows x numCols Units, see InitializeEcosystem().
+// The prey and predators are shared out evenly over the layers, and the initial food of every layer is thinned out in proportion to its DepthLight().
+// With disease, every layer starts with numInitiallyInfected infected organisms.
+func InitializeOcean(numRows, numCols, numPrey, numPred int) Ocean {
+	newOcean := make(Ocean, numLayers)
+	for layer := range newOcean {
+		layerPrey := numPrey / numLayers
+		if layer < numPrey%numLayers {
+			layerPrey++
+		}
+		layerPred := numPred / numLayers
+		if layer < numPred%numLayers {
+			layerPred++
+		}
+
+		newLayer := InitializeEcosystem(numRows, numCols, layerPrey, layerPred)
+		for i := range newLayer {
+			for j := range newLayer[i] {
+				if newLayer[i][j].food.isPresent && rng.Float64() >= DepthLight(layer) {
+					newLayer[i][j].food = Food{}
+				}
+			}
+		}
+		newOcean[layer] = &newLayer
+	}
+	return newOcean
+}
+
+// SimulateOcean() is SimulateEcosystemEvolution() for an Ocean with depth layers. The stats of every generation are taken over all layers
+// and stored in allStats. Interventions only apply to a flat ocean.
+func SimulateOcean(initialOcean *Ocean, totalTimesteps int, foodRule string) []*Ocean {
+	fmt.Println("SimulateOcean is running")
+
+	allOceans := make([]*Ocean, totalTimesteps+1)
+	allOceans[0] = initialOcean
+
+	ResetStats(0)
+	curStats.preyLedger.introduced, curStats.predLedger.introduced = SpeciesEnergy(StackLayers(initialOcean))
+	allStats = []GenerationStats{FinishOceanStats(initialOcean)}
+
+	var start time.Time = time.Now()
+
+	for i := 1; i <= totalTimesteps; i++ {
+		allOceans[i] = UpdateOcean(allOceans[i-1], foodRule, i)
+		allStats = append(allStats, FinishOceanStats(allOceans[i]))
+
+		// print status of simulation
+		if (totalTimesteps / 10) != 0 {
+			if i%(totalTimesteps/10) == 0 || i == 1 {
+				fmt.Println("Simulation is", 100*float64(i)/float64(totalTimesteps), "percent complete. Generation =", i)
+				elapsed := time.Since(start)
+				log.Printf("This took total %s\n\n", elapsed)
+			}
+		}
+	}
+
+	return allOceans
+}
+
+// UpdateOcean() updates every layer of prevOcean the way UpdateEcosystem() updates a flat one, with food growing in proportion to the DepthLight()
+// of the layer and predators hunting in its Light(). Once every layer is done, Harvest() fishes all the layers together with one harvestPolicy,
+// and the organisms move between layers, see MoveVertically().
+func UpdateOcean(prevOcean *Ocean, foodRule string, curGen int) *Ocean {
+	ResetStats(curGen)
+	curStats.isNight = DielPhase(curGen) == night
+
+	nextOcean := make(Ocean, len(*prevOcean))
+	for layer := range *prevOcean {
+		nextOcean[layer] = DeepCopyEcosystem((*prevOcean)[layer])
+	}
+	curStats.preyLedger.startEnergy, curStats.predLedger.startEnergy = SpeciesEnergy(StackLayers(&nextOcean))
+
+	for layer := range nextOcean {
+		curLight = Light(layer, curGen)
+		UpdateUnits(nextOcean[layer], foodRule, curGen, DepthLight(layer))
+	}
+	curLight = 1
+
+	Harvest(nextOcean...)
+	MoveVertically(&nextOcean, curGen)
+
+	return &nextOcean
+}
+
+// MoveVertically() gives every organism of someOcean, in random order, the chance to move up or down a layer with the weights of its VerticalGenome
+// for the current phase of the day. An organism only moves into the Unit right above or below it, and only if that Unit has neither prey nor predator
+// (and, for prey, room in its refuge, for predators no refuge). Organisms can't leave the ocean through the surface or the bottom. Every move costs verticalMoveCost energy.
+func MoveVertically(someOcean *Ocean, curGen int) {
+	phase := DielPhase(curGen)
+
+	// list the organisms before moving any, so nobody moves twice
+	type location struct {
+		layer, row, col int
+	}
+	var locations []location
+	for layer, someEcosystem := range *someOcean {
+		for i := range *someEcosystem {
+			for j := range (*someEcosystem)[i] {
+				if (*someEcosystem)[i][j].prey != nil || (*someEcosystem)[i][j].predator != nil {
+					locations = append(locations, location{layer, i, j})
+				}
+			}
+		}
+	}
+	rng.Shuffle(len(locations), func(a, b int) {
+		locations[a], locations[b] = locations[b], locations[a]
+	})
+
+	for _, here := range locations {
+		curUnit := (*(*someOcean)[here.layer])[here.row][here.col]
+
+		var genome VerticalGenome
+		if curUnit.prey != nil {
+			genome = curUnit.prey.verticalGenome
+		} else {
+			genome = curUnit.predator.verticalGenome
+		}
+		newLayer := here.layer + ChooseVerticalMove(genome[phase])
+		if newLayer == here.layer || newLayer < 0 || newLayer >= len(*someOcean) {
+			continue
+		}
+
+		targetEcosystem := (*someOcean)[newLayer]
+		target := (*targetEcosystem)[here.row][here.col]
+		if target.prey != nil || target.predator != nil {
+			continue
+		}
+
+		if curUnit.prey != nil {
+			if !target.refuge.HasRoom(targetEcosystem, nil) {
+				continue
+			}
+			target.prey, curUnit.prey = curUnit.prey, nil
+			target.prey.energy -= verticalMoveCost
+			curStats.preyLedger.moving += verticalMoveCost
+		} else {
+			if target.refuge != nil {
+				continue
+			}
+			target.predator, curUnit.predator = curUnit.predator, nil
+			target.predator.energy -= verticalMoveCost
+			curStats.predLedger.moving += verticalMoveCost
+		}
+	}
+}
+
+// StackLayers() returns an Ecosystem with the rows of every layer of someOcean one after the other, surface first. it shares the Units of someOcean,
+// so functions that go over all Units (stats, energy, genome files) see the whole ocean. neighbour counts treat the layers as stacked,
+// so they are slightly off along the first and last rows of each layer.
+func StackLayers(someOcean *Ocean) *Ecosystem {
+	var stacked Ecosystem
+	for _, someEcosystem := range *someOcean {
+		stacked = append(stacked, *someEcosystem...)
+	}
+	return &stacked
+}
+
+// FinishOceanStats() is FinishStats() over every layer of someOcean, plus the depth stats. the mean rows are worked out again within each layer,
+// since FinishStats() counts the rows of the stacked layers.
+func FinishOceanStats(someOcean *Ocean) GenerationStats {
+	stats := FinishStats(StackLayers(someOcean))
+	stats.meanPreyRow, stats.meanPredRow = 0, 0
+
+	for layer, someEcosystem := range *someOcean {
+		for i := range *someEcosystem {
+			for j := range (*someEcosystem)[i] {
+				curUnit := (*someEcosystem)[i][j]
+				if curUnit.prey != nil {
+					stats.meanPreyRow += float64(i)
+					stats.meanPreyDepth += float64(layer)
+					stats.preyDielMigration += DielMigration(curUnit.prey.verticalGenome)
+				}
+				if curUnit.predator != nil {
+					stats.meanPredRow += float64(i)
+					stats.meanPredDepth += float64(layer)
+					stats.predDielMigration += DielMigration(curUnit.predator.verticalGenome)
+				}
+			}
+		}
+	}
+
+	if stats.numPrey != 0 {
+		stats.meanPreyRow /= float64(stats.numPrey)
+		stats.meanPreyDepth /= float64(stats.numPrey)
+		stats.preyDielMigration /= float64(stats.numPrey)
+	}
+	if stats.numPred != 0 {
+		stats.meanPredRow /= float64(stats.numPred)
+		stats.meanPredDepth /= float64(stats.numPred)
+		stats.predDielMigration /= float64(stats.numPred)
+	}
+	return stats
+}
+
+// OceanView() returns the Ecosystem that is drawn for someOcean, according to depthView. "slice" is layer depthSlice,
+// and "projection" looks down on the ocean from above: every Unit shows the shallowest organism below it, and the shallowest food if there is no organism.
+func OceanView(someOcean *Ocean) *Ecosystem {
+	if depthView == "slice" {
+		if depthSlice < 0 || depthSlice >= len(*someOcean) {
+			panic(fmt.Sprintf("depthSlice %d is not a layer of an ocean with %d layers", depthSlice, len(*someOcean)))
+		}
+		return (*someOcean)[depthSlice]
+	} else if depthView != "projection" {
+		panic("invalid depthView string inputted. should be slice or projection!")
+	}
+
+	surface := (*someOcean)[0]
+	projection := make(Ecosystem, surface.CountRows())
+	for i := range projection {
+		projection[i] = make([]*Unit, surface.CountCols())
+		for j := range projection[i] {
+			projection[i][j] = new(Unit)
+			projection[i][j].refuge = (*surface)[i][j].refuge
+
+			// go down until we've found both an organism and food. a Unit never holds both a prey and a predator
+			foundOrganism := false
+			for _, someEcosystem := range *someOcean {
+				curUnit := (*someEcosystem)[i][j]
+				if !foundOrganism && (curUnit.prey != nil || curUnit.predator != nil) {
+					projection[i][j].prey = curUnit.prey
+					projection[i][j].predator = curUnit.predator
+					foundOrganism = true
+				}
+				if !projection[i][j].food.isPresent && curUnit.food.isPresent {
+					projection[i][j].food = curUnit.food
+				}
+			}
+		}
+	}
+	return &projection
+}
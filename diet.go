@@ -0,0 +1,152 @@
+package main
+
+// FoodType is one kind of food, e.g. diatoms, copepods or algae. A Unit holds at most one food item, and food.foodType says which type it is.
+// Each type has its own energy, spawn rule and colour. spawnRule is one of the food rules (see GeneratePreyFoodProbabilistically()),
+// or "" to follow the food rule of the simulation. A type with a spawnRegion only grows inside it, which lets us separate the food types in space.
+type FoodType struct {
+	name         string
+	energyGained int // what a prey gets from one item under the "fixed" energyTransferRule
+	energyHeld   int // energy held by one item, what the "fraction" rules take their share of
+	spawnRule    string
+	spawnRegion  Region // nil to grow anywhere
+	red          uint8
+	green        uint8
+	blue         uint8
+}
+
+// NumFoodTypes() is the number of food types. without any foodTypes there is a single type, the original plankton.
+func NumFoodTypes() int {
+	if len(foodTypes) == 0 {
+		return 1
+	}
+	return len(foodTypes)
+}
+
+// FoodTypeName() returns the name of food type foodType, "plankton" for the original plankton.
+func FoodTypeName(foodType int) string {
+	if len(foodTypes) == 0 {
+		return "plankton"
+	}
+	return foodTypes[foodType].name
+}
+
+// FoodEnergy() returns the energy a prey gets from food of type foodType under the "fixed" rule, and the energy it holds.
+// without any foodTypes these are energyGainedPerPlankton and planktonEnergy.
+func FoodEnergy(foodType int) (int, int) {
+	if len(foodTypes) == 0 {
+		return energyGainedPerPlankton, planktonEnergy
+	}
+	return foodTypes[foodType].energyGained, foodTypes[foodType].energyHeld
+}
+
+// FoodColour() returns the colour food of type foodType is drawn in. the original plankton is green.
+func FoodColour(foodType int) (uint8, uint8, uint8) {
+	if len(foodTypes) == 0 {
+		return 0, 255, 0
+	}
+	return foodTypes[foodType].red, foodTypes[foodType].green, foodTypes[foodType].blue
+}
+
+// CanGrowAt() checks whether food of type foodType may grow in Unit row, col.
+func CanGrowAt(foodType, row, col int) bool {
+	if len(foodTypes) == 0 || foodTypes[foodType].spawnRegion == nil {
+		return true
+	}
+	return foodTypes[foodType].spawnRegion.Contains(row, col)
+}
+
+// RandomFoodType() picks a random food type that may grow in Unit row, col. it returns -1 if none may.
+func RandomFoodType(row, col int) int {
+	var allowed []int
+	for foodType := 0; foodType < NumFoodTypes(); foodType++ {
+		if CanGrowAt(foodType, row, col) {
+			allowed = append(allowed, foodType)
+		}
+	}
+	if len(allowed) == 0 {
+		return -1
+	}
+	return allowed[rng.Intn(len(allowed))]
+}
+
+// FounderDiet() is the diet trait of the founding prey: the same preference for every food type, a generalist.
+func FounderDiet() []float64 {
+	diet := make([]float64, NumFoodTypes())
+	for foodType := range diet {
+		diet[foodType] = 1 / float64(len(diet))
+	}
+	return diet
+}
+
+// DietEfficiency() is how well a prey with the given diet trait digests food of type foodType: its preference for it, the share of the food's energy it gets.
+// The preferences add up to 1, so no diet gets more than the food holds. A specialist gets all of it from its favourite type and nothing from the others,
+// while a generalist gets 1 / the number of types from every type. organisms without a diet (predators) always get 1.
+func DietEfficiency(diet []float64, foodType int) float64 {
+	if len(diet) == 0 {
+		return 1
+	}
+	return diet[foodType]
+}
+
+// WillEat() checks whether currentPrey eats someFood. prey skip food types they prefer less than minDietPreference.
+func (currentPrey *Prey) WillEat(someFood Food) bool {
+	if !someFood.isPresent {
+		return false
+	}
+	if len(currentPrey.traits.diet) == 0 {
+		return true
+	}
+	return currentPrey.traits.diet[someFood.foodType] >= minDietPreference
+}
+
+// InheritDiet() returns a child's diet trait: parentDiet with Gaussian noise of traitMutationStrength on every preference, renormalised.
+// it always makes a new slice, so diets are never shared between organisms that could change them.
+func InheritDiet(parentDiet []float64) []float64 {
+	if len(parentDiet) == 0 {
+		return nil
+	}
+	diet := make([]float64, len(parentDiet))
+	for foodType := range parentDiet {
+		diet[foodType] = MutateTrait(parentDiet[foodType], traitMutationStrength/float64(len(parentDiet)))
+	}
+	return NormalizeDiet(diet)
+}
+
+// BlendDiet() averages the diet traits of two parents, for sexual reproduction.
+func BlendDiet(diet1, diet2 []float64) []float64 {
+	if len(diet1) == 0 || len(diet2) == 0 {
+		return diet1
+	}
+	diet := make([]float64, len(diet1))
+	for foodType := range diet {
+		diet[foodType] = (diet1[foodType] + diet2[foodType]) / 2
+	}
+	return diet
+}
+
+// NormalizeDiet() rescales the preferences of diet, in place, so that they add up to 1. a diet of all zeros becomes a generalist.
+func NormalizeDiet(diet []float64) []float64 {
+	sum := 0.0
+	for _, preference := range diet {
+		sum += preference
+	}
+	for foodType := range diet {
+		if sum == 0 {
+			diet[foodType] = 1 / float64(len(diet))
+		} else {
+			diet[foodType] /= sum
+		}
+	}
+	return diet
+}
+
+// DietSpecialisation() is the highest preference in diet. it is 1/NumFoodTypes() for a generalist and 1 for a prey that only eats one type.
+func DietSpecialisation(diet []float64) float64 {
+	highest := 0.0
+	for _, preference := range diet {
+		if preference > highest {
+			highest = preference
+		}
+	}
+	return highest
+}
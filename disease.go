@@ -0,0 +1,105 @@
+package main
+
+// InfectRandomOrganisms() infects numInfected organisms (prey or predators) chosen at random from someEcosystem.
+// if there are fewer organisms than that, all of them are infected.
+func InfectRandomOrganisms(someEcosystem *Ecosystem, numInfected int) {
+	organisms := AllOrganisms(someEcosystem)
+	rng.Shuffle(len(organisms), func(a, b int) {
+		organisms[a], organisms[b] = organisms[b], organisms[a]
+	})
+
+	for k := 0; k < numInfected && k < len(organisms); k++ {
+		organisms[k].infection = infected
+		organisms[k].infectedFor = 0
+	}
+}
+
+// AllOrganisms() returns a pointer to the Organism of every prey and predator in someEcosystem.
+func AllOrganisms(someEcosystem *Ecosystem) []*Organism {
+	var organisms []*Organism
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].prey != nil {
+				organisms = append(organisms, &(*someEcosystem)[i][j].prey.Organism)
+			}
+			if (*someEcosystem)[i][j].predator != nil {
+				organisms = append(organisms, &(*someEcosystem)[i][j].predator.Organism)
+			}
+		}
+	}
+	return organisms
+}
+
+// UpdateDisease() runs one generation of the disease on someEcosystem, after everybody has moved.
+// Every infected organism loses infectionEnergyDrain energy, dies of the disease with probability diseaseMortality, recovers after recoveryTime generations,
+// and otherwise infects each susceptible organism among its 8 neighbours with probability transmissionProbability.
+// New infections only take effect at the end, so the disease spreads by at most one Unit per generation.
+func UpdateDisease(someEcosystem *Ecosystem) {
+	numRows := someEcosystem.CountRows()
+	numCols := someEcosystem.CountCols()
+	var newlyInfected []*Organism
+
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curUnit := (*someEcosystem)[i][j]
+
+			if curUnit.prey != nil && curUnit.prey.infection == infected {
+				if ProgressInfection(&curUnit.prey.Organism, &curStats.preyLedger) {
+					curStats.preyLedger.lostAtDeath += curUnit.prey.energy
+					DepositCarcass(curUnit, curUnit.prey.energy)
+					curUnit.prey = nil
+					curStats.preyDiedOfDisease++
+				} else {
+					newlyInfected = append(newlyInfected, InfectNeighbours(someEcosystem, i, j, numRows, numCols)...)
+				}
+			}
+
+			if curUnit.predator != nil && curUnit.predator.infection == infected {
+				if ProgressInfection(&curUnit.predator.Organism, &curStats.predLedger) {
+					curStats.predLedger.lostAtDeath += curUnit.predator.energy
+					DepositCarcass(curUnit, curUnit.predator.energy)
+					curUnit.predator = nil
+					curStats.predDiedOfDisease++
+				} else {
+					newlyInfected = append(newlyInfected, InfectNeighbours(someEcosystem, i, j, numRows, numCols)...)
+				}
+			}
+		}
+	}
+
+	for _, someOrganism := range newlyInfected {
+		someOrganism.infection = infected
+		someOrganism.infectedFor = 0
+	}
+}
+
+// ProgressInfection() drains the energy of an infected organism, recording it in the ledger of its species, and moves it one generation further through the disease.
+// Output: true if the organism dies of the disease
+func ProgressInfection(someOrganism *Organism, ledger *EnergyLedger) bool {
+	someOrganism.energy -= infectionEnergyDrain
+	ledger.disease += infectionEnergyDrain
+	if rng.Float64() < diseaseMortality {
+		return true
+	}
+
+	someOrganism.infectedFor += 1
+	if someOrganism.infectedFor >= recoveryTime {
+		someOrganism.infection = recovered
+	}
+	return false
+}
+
+// InfectNeighbours() picks which susceptible organisms among the 8 neighbours of Unit i, j catch the disease this generation.
+func InfectNeighbours(someEcosystem *Ecosystem, i, j, numRows, numCols int) []*Organism {
+	var caught []*Organism
+	for _, moveDeltas := range deltas {
+		neighbour := (*someEcosystem)[GetIndex(i, moveDeltas.row, numRows)][GetIndex(j, moveDeltas.col, numCols)]
+		if neighbour.prey != nil && neighbour.prey.infection == susceptible && rng.Float64() < transmissionProbability {
+			caught = append(caught, &neighbour.prey.Organism)
+		}
+		if neighbour.predator != nil && neighbour.predator.infection == susceptible && rng.Float64() < transmissionProbability {
+			caught = append(caught, &neighbour.predator.Organism)
+		}
+	}
+	return caught
+}
@@ -4,6 +4,7 @@ import (
 	"canvas"
 	"fmt"
 	"image"
+	"image/color"
 	"math"
 )
 
@@ -27,7 +28,7 @@ func AnimateSystem(allEcosystems []*Ecosystem, canvasWidth, frequency int, scali
 		}
 
 		// print status of image drawing
-		if i%(numberImages/10) == 0 || i == 0 {
+		if numberImages < 10 || i%(numberImages/10) == 0 {
 			number := float64(i) / float64(numberImages)
 			number = math.Round(number * 100)
 			fmt.Println("Drawing is", number, "percent complete")
@@ -61,11 +62,11 @@ func (eco *Ecosystem) DrawToCanvas(canvasWidth int, scalingFactor float64) image
 	c.ClearRect(0, 0, canvasWidth, canvasWidth)
 	c.Fill()
 
-	// colors for each unit type
-	var food_red uint8 = 0
-	var food_green uint8 = 255
-	var food_blue uint8 = 0
-	foodColor := canvas.MakeColor(food_red, food_green, food_blue)
+	// colors for each unit type. every food type has its own colour, see FoodColour()
+	foodColors := make([]color.Color, NumFoodTypes())
+	for foodType := range foodColors {
+		foodColors[foodType] = canvas.MakeColor(FoodColour(foodType))
+	}
 
 	var prey_red uint8 = 0
 	var prey_green uint8 = 0
@@ -77,14 +78,29 @@ func (eco *Ecosystem) DrawToCanvas(canvasWidth int, scalingFactor float64) image
 	var pred_blue uint8 = 0
 	predColor := canvas.MakeColor(pred_red, pred_green, pred_blue)
 
+	// refuges are drawn as a pale overlay under everything else
+	refugeColor := canvas.MakeColor(200, 235, 230)
+
+	// infected prey are drawn purple and infected predators orange
+	infectedPreyColor := canvas.MakeColor(160, 0, 255)
+	infectedPredColor := canvas.MakeColor(255, 160, 0)
+
 	// range over all the Units and draw them.
 	for i := range *eco {
 		for j := range (*eco)[i] {
 			curUnit := (*eco)[i][j]
 
+			if curUnit.refuge != nil {
+				c.SetFillColor(refugeColor)
+				x := j * unitWidth
+				y := i * unitWidth
+				c.ClearRect(x, y, x+unitWidth, y+unitWidth)
+				c.Fill()
+			}
+
 			//food can be present at the same time as shark or prey
 			if curUnit.food.isPresent {
-				c.SetFillColor(foodColor)
+				c.SetFillColor(foodColors[curUnit.food.foodType])
 				x := j * unitWidth
 				y := i * unitWidth
 				c.ClearRect(x, y, x+unitWidth, y+unitWidth)
@@ -93,13 +109,21 @@ func (eco *Ecosystem) DrawToCanvas(canvasWidth int, scalingFactor float64) image
 
 			// prey and predator can never overlap
 			if curUnit.prey != nil {
-				c.SetFillColor(preyColor)
+				if curUnit.prey.infection == infected {
+					c.SetFillColor(infectedPreyColor)
+				} else {
+					c.SetFillColor(preyColor)
+				}
 				x := j * unitWidth
 				y := i * unitWidth
 				c.ClearRect(x, y, x+unitWidth, y+unitWidth)
 				c.Fill()
 			} else if curUnit.predator != nil {
-				c.SetFillColor(predColor)
+				if curUnit.predator.infection == infected {
+					c.SetFillColor(infectedPredColor)
+				} else {
+					c.SetFillColor(predColor)
+				}
 				x := j * unitWidth
 				y := i * unitWidth
 				c.ClearRect(x, y, x+unitWidth, y+unitWidth)
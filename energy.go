@@ -0,0 +1,25 @@
+package main
+
+// EnergyTransferred() returns how much energy an eater gets from one meal, under energyTransferRule.
+// "fixed" always gives fixedGain. "fraction" gives trophicEfficiency of the energy held by the food (foodEnergy), the rest is lost to the food web.
+// "cappedFraction" is "fraction" but never takes the eater above maxEnergy.
+// Input: the eater's current energy, the energy held by the food, and the flat amount used by the "fixed" rule
+// Output: the energy to add to the eater, never negative
+func EnergyTransferred(eaterEnergy, foodEnergy, fixedGain int) int {
+	var gain int
+	if energyTransferRule == "fixed" {
+		gain = fixedGain
+	} else if energyTransferRule == "fraction" || energyTransferRule == "cappedFraction" {
+		gain = int(trophicEfficiency * float64(foodEnergy))
+		if energyTransferRule == "cappedFraction" && eaterEnergy+gain > maxEnergy {
+			gain = maxEnergy - eaterEnergy
+		}
+	} else {
+		panic("invalid energyTransferRule string inputted. should be fixed, fraction, or cappedFraction!")
+	}
+
+	if gain < 0 {
+		gain = 0
+	}
+	return gain
+}
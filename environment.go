@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// boltzmannConstant in eV per kelvin, for the "arrhenius" thermalRule
+const boltzmannConstant float64 = 8.617e-5
+
+// EnvironmentField is a value that can change over the Ecosystem and over time, like the temperature (in degrees Celsius) or the light.
+// rule picks how it varies in space: "static" is value everywhere, "gradient" goes linearly from value in the first row to gradientEnd in the last,
+// like a north-south gradient, and "file" reads one value per Unit from filename (see LoadFieldFromFile()).
+// On top of any rule, the field swings by seasonalAmplitude over a year of seasonLength generations (no seasons if seasonalAmplitude is 0),
+// and changes by trend every generation, e.g. for a climate-warming scenario.
+type EnvironmentField struct {
+	rule              string
+	value             float64
+	gradientEnd       float64
+	filename          string
+	seasonalAmplitude float64
+	seasonLength      int
+	trend             float64
+
+	values [][]float64 // loaded from filename by main
+}
+
+// At() returns the value of field in Unit row, col of an Ecosystem with numRows rows, in generation curGen.
+func (field EnvironmentField) At(row, col, numRows, curGen int) float64 {
+	var value float64
+	if field.rule == "static" {
+		value = field.value
+	} else if field.rule == "gradient" {
+		share := 0.0
+		if numRows > 1 {
+			share = float64(row) / float64(numRows-1)
+		}
+		value = field.value + share*(field.gradientEnd-field.value)
+	} else if field.rule == "file" {
+		if row >= len(field.values) || col >= len(field.values[row]) {
+			panic("the field in " + field.filename + " is smaller than the Ecosystem")
+		}
+		value = field.values[row][col]
+	} else {
+		panic("invalid environment field rule string inputted. should be static, gradient, or file!")
+	}
+
+	if field.seasonalAmplitude != 0 {
+		if field.seasonLength <= 0 {
+			panic("a seasonal environment field needs a seasonLength of at least 1 generation")
+		}
+		value += field.seasonalAmplitude * math.Sin(2*math.Pi*float64(curGen)/float64(field.seasonLength))
+	}
+	return value + field.trend*float64(curGen)
+}
+
+// IsTimeDependent() is true if field changes from one generation to the next, i.e. it has seasons or a trend.
+func (field EnvironmentField) IsTimeDependent() bool {
+	return field.seasonalAmplitude != 0 || field.trend != 0
+}
+
+// UpdateEnvironment() fills in the temperature and light of every Unit of someEcosystem in generation curGen from temperatureField and lightField.
+// it is called before the Units are updated, so everybody sees the same environment during a generation. DeepCopyEcosystem() copies the values,
+// so after generation 0 a field is only worked out again if it IsTimeDependent(). every layer of an Ocean has the temperature and light of the surface.
+func UpdateEnvironment(someEcosystem *Ecosystem, curGen int) {
+	updateTemperature := curGen == 0 || temperatureField.IsTimeDependent()
+	updateLight := curGen == 0 || lightField.IsTimeDependent()
+	if !updateTemperature && !updateLight {
+		return
+	}
+
+	numRows := someEcosystem.CountRows()
+	for i := range *someEcosystem {
+		for j, curUnit := range (*someEcosystem)[i] {
+			if updateTemperature {
+				curUnit.temperature = temperatureField.At(i, j, numRows, curGen)
+			}
+			if updateLight {
+				curUnit.light = lightField.At(i, j, numRows, curGen)
+			}
+		}
+	}
+}
+
+// ThermalFactor() is how much faster biological rates run at temperature than at referenceTemperature, according to thermalRule:
+// "none" is always 1, "q10" is q10^((temperature - referenceTemperature) / 10), and "arrhenius" is the Boltzmann-Arrhenius factor
+// exp(activationEnergy / k * (1/Tref - 1/T)) with the temperatures in kelvin, as in the metabolic theory of ecology.
+func ThermalFactor(temperature float64) float64 {
+	if thermalRule == "none" {
+		return 1
+	} else if thermalRule == "q10" {
+		return math.Pow(q10, (temperature-referenceTemperature)/10)
+	} else if thermalRule == "arrhenius" {
+		return math.Exp(activationEnergy / boltzmannConstant * (1/(referenceTemperature+273.15) - 1/(temperature+273.15)))
+	}
+	panic("invalid thermalRule string inputted. should be none, q10, or arrhenius!")
+}
+
+// LocalThermalFactor() is the ThermalFactor() of curUnit in the current generation.
+func LocalThermalFactor(curUnit *Unit) float64 {
+	return ThermalFactor(curUnit.temperature)
+}
+
+// ScaledCost() multiplies cost by thermalFactor, e.g. the basal metabolic cost by the ThermalFactor() of the organism's Unit. the result is rounded with StochasticRound(), so a small metabolism still feels the temperature
+// and the cost isn't biased.
+func ScaledCost(cost int, thermalFactor float64) int {
+	return StochasticRound(float64(cost) * thermalFactor)
+}
+
+// StochasticRound() rounds value up or down at random in proportion to the fraction, so on average it is value.
+func StochasticRound(value float64) int {
+	rounded := math.Floor(value)
+	if value > rounded && rng.Float64() < value-rounded {
+		rounded++
+	}
+	return int(rounded)
+}
+
+// ScaledAge() is ageThreshold divided by thermalFactor, rounded to the nearest generation: the number of generations since birth or the last reproduction
+// an organism with the given ageThreshold trait needs before it can reproduce in a Unit with that ThermalFactor(). organisms develop faster where it is warm.
+// the energy threshold is an amount of energy, not a rate, so it doesn't depend on the temperature.
+func ScaledAge(ageThreshold int, thermalFactor float64) int {
+	return int(math.Round(float64(ageThreshold) / thermalFactor))
+}
+
+// FoodGrowth() is the share of its usual probability with which food grows in curUnit: the light times LocalThermalFactor().
+// it can't make food grow more often than the food rule allows, so referenceTemperature should be about the warmest temperature food sees.
+func FoodGrowth(curUnit *Unit) float64 {
+	return curUnit.light * LocalThermalFactor(curUnit)
+}
+
+// UnitThermalFactor() is LocalThermalFactor() of Unit i, j of an Ecosystem with numRows rows in generation curGen, worked out on the spot
+// for the engines that don't keep a temperature in every Unit.
+func UnitThermalFactor(i, j, numRows, curGen int) float64 {
+	if thermalRule == "none" {
+		return 1
+	}
+	return ThermalFactor(temperatureField.At(i, j, numRows, curGen))
+}
+
+// UnitFoodProbability() is the chance food grows in the empty Unit i, j in generation curGen: FoodProbability() of foodRule scaled by FoodGrowth(),
+// worked out on the spot like UnitThermalFactor().
+func UnitFoodProbability(foodRule string, i, j, numRows, numCols, curGen int) float64 {
+	growth := lightField.At(i, j, numRows, curGen) * UnitThermalFactor(i, j, numRows, curGen)
+	if growth > 1 {
+		growth = 1
+	}
+	return growth * FoodProbability(foodRule, i, j, numRows, numCols)
+}
+
+// LoadFieldFromFile() reads an EnvironmentField from a text file with one line per row of the Ecosystem, and the values of the row separated by spaces or commas.
+// Lines starting with // are skipped.
+func LoadFieldFromFile(filename string) [][]float64 {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic("could not open field file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	var values [][]float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		var fieldRow []float64
+		for _, word := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+			value, err := strconv.ParseFloat(word, 64)
+			if err != nil {
+				panic("invalid value " + word + " in field file " + filename + ": " + err.Error())
+			}
+			fieldRow = append(fieldRow, value)
+		}
+		values = append(values, fieldRow)
+	}
+
+	if err := scanner.Err(); err != nil {
+		panic("could not read field file " + filename + ": " + err.Error())
+	}
+
+	return values
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+)
+
+// FlockingVectors() looks at the prey within radius (Chebyshev distance, wrapping around the edges) of Unit i, j and returns three (row, col) vectors:
+// heading is the mean of the neighbours' lastDirection (alignment), centre points to the middle of the neighbours (cohesion),
+// and crowding points towards the neighbours that are right next to the prey (separation, so it is used to push away).
+// heading and centre have length at most 1, so the flocking weights are comparable to each other. all three are zero without neighbours.
+func FlockingVectors(board Board, i, j, radius int) ([2]float64, [2]float64, [2]float64) {
+	var heading, centre, crowding [2]float64
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+	numNeighbours := 0
+
+	for deltaRow := -radius; deltaRow <= radius; deltaRow++ {
+		for deltaCol := -radius; deltaCol <= radius; deltaCol++ {
+			if deltaRow == 0 && deltaCol == 0 {
+				continue
+			}
+
+			neighbour := board.PreyAt(GetIndex(i, deltaRow, numRows), GetIndex(j, deltaCol, numCols))
+			if neighbour == nil {
+				continue
+			}
+			numNeighbours++
+
+			// alignment: add up the unit vectors of the directions the neighbours last moved in
+			neighbourDeltas := deltas[neighbour.lastDirection]
+			length := math.Sqrt(float64(neighbourDeltas.row*neighbourDeltas.row + neighbourDeltas.col*neighbourDeltas.col))
+			heading[0] += float64(neighbourDeltas.row) / length
+			heading[1] += float64(neighbourDeltas.col) / length
+
+			// cohesion: add up the offsets to the neighbours
+			centre[0] += float64(deltaRow)
+			centre[1] += float64(deltaCol)
+
+			// separation: only the neighbours that are touching count
+			if Abs(deltaRow) <= 1 && Abs(deltaCol) <= 1 {
+				distance := math.Sqrt(float64(deltaRow*deltaRow + deltaCol*deltaCol))
+				crowding[0] += float64(deltaRow) / distance
+				crowding[1] += float64(deltaCol) / distance
+			}
+		}
+	}
+
+	if numNeighbours == 0 {
+		return heading, centre, crowding
+	}
+
+	heading[0] /= float64(numNeighbours)
+	heading[1] /= float64(numNeighbours)
+
+	// scale the offset to the centre of the neighbours down to a unit vector
+	centreLength := math.Sqrt(centre[0]*centre[0] + centre[1]*centre[1])
+	if centreLength > 0 {
+		centre[0] /= centreLength
+		centre[1] /= centreLength
+	}
+
+	return heading, centre, crowding
+}
@@ -2,19 +2,44 @@ package main
 
 import (
 	"math/rand"
-	"time"
 )
 
 // GeneratePreyFoodProbabilistically() is a method operating on a Unit pointer someUnit. it uses some probabilistic function determined by foodRule, to determine whether food will be generated in this Unit or not. NOTE: this function shouldn't be called if there is something else in the Unit already
 // Input: foodRule string, row and col indices for the Unit, and a Ecosystem pointer someEcosystem
+// With foodTypes, every food type tries to grow with its own spawn rule, and the food that appears remembers its type.
 // Output: none. operates on a pointer
 func (someUnit *Unit) GeneratePreyFoodProbabilistically(foodRule string, row, col int, someEcosystem *Ecosystem) {
-	//don't want a race condition, where all processes share a single PRNG object
-	source := rand.NewSource(time.Now().UnixNano()) // create new PRNG object
-	generator := rand.New(source)
+	// the food draws from rng like everything else, so a seeded run can be repeated
+	generator := rng
 	numRows := someEcosystem.CountRows()
 	numCols := someEcosystem.CountCols()
 
+	if len(foodTypes) == 0 {
+		someUnit.GenerateFoodByRule(foodRule, row, col, numRows, numCols, generator)
+		return
+	}
+
+	// every food type gets its own chance to grow here with its own spawn rule, starting from a random type so none of them is favoured
+	firstType := generator.Intn(len(foodTypes))
+	for k := range foodTypes {
+		foodType := (firstType + k) % len(foodTypes)
+		if !CanGrowAt(foodType, row, col) {
+			continue
+		}
+		spawnRule := foodTypes[foodType].spawnRule
+		if spawnRule == "" {
+			spawnRule = foodRule
+		}
+		someUnit.GenerateFoodByRule(spawnRule, row, col, numRows, numCols, generator)
+		if someUnit.food.isPresent {
+			someUnit.food.foodType = foodType
+			return
+		}
+	}
+}
+
+// GenerateFoodByRule() makes food appear in someUnit with the probabilities of foodRule, see GeneratePreyFoodProbabilistically()
+func (someUnit *Unit) GenerateFoodByRule(foodRule string, row, col, numRows, numCols int, generator *rand.Rand) {
 	if foodRule == "gardenOfEden" {
 		someUnit.GenerateEden(row, col, numRows, numCols, generator)
 	} else if foodRule == "even" {
@@ -102,6 +127,40 @@ func (someUnit *Unit) GenerateLineRunner(row, col, numRows, numCols int, generat
 	}
 }
 
+// FoodProbability() is the chance that food grows in an empty Unit row, col under foodRule: the same chances GenerateEden(), GenerateRandom()
+// and GenerateLineRunner() use. the sparse backend needs them to place food without visiting every Unit, see SparseEcosystem
+func FoodProbability(foodRule string, row, col, numRows, numCols int) float64 {
+	if foodRule == "gardenOfEden" {
+		fractionOfBoard := 10
+		if CheckIsInCenter(row, col, numRows/2, numCols/2, numCols/fractionOfBoard, numRows/fractionOfBoard) {
+			return 0.10
+		}
+		return 0.01
+	} else if foodRule == "even" {
+		return 0.001
+	} else if foodRule == "lineRunner" {
+		gridRow := numRows / 4
+		gridCol := numCols / 4
+		if CheckIsOnGridLine(&row, &col, &gridRow, &gridCol) {
+			return 0.05
+		}
+		return 0.00001
+	}
+	panic("invalid foodRule string inputted. should be eden, random, or lineRunner!")
+}
+
+// MaxFoodProbability() is the highest FoodProbability() of any Unit under foodRule.
+func MaxFoodProbability(foodRule string) float64 {
+	if foodRule == "gardenOfEden" {
+		return 0.10
+	} else if foodRule == "even" {
+		return 0.001
+	} else if foodRule == "lineRunner" {
+		return 0.05
+	}
+	panic("invalid foodRule string inputted. should be eden, random, or lineRunner!")
+}
+
 func CheckIsOnGridLine(row, col, gridRow, gridCol *int) bool {
 	if *row == 0 || *col == 0 {
 		return false
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FounderGenome is one line of a genome file: the direction genome of an organism and its VerticalGenome.
+type FounderGenome struct {
+	genome         [8]Gene
+	verticalGenome VerticalGenome
+}
+
+// founderGenomes holds the genomes read by LoadGenomesFromFile, keyed by species ("prey" or "predator").
+// CreateGenome draws from it when the genomeRule is "file".
+var founderGenomes map[string][]FounderGenome
+
+// DirichletGenome draws a random genome from a symmetric Dirichlet distribution with the given concentration.
+// Input: concentration > 0. values below 1 favour genomes dominated by a few genes, values above 1 favour genomes close to uniform
+// Output: a genome whose genes add up to 1
+func DirichletGenome(concentration float64) [8]Gene {
+	if concentration <= 0 {
+		panic("dirichletConcentration must be positive")
+	}
+
+	// a Dirichlet sample is a vector of independent Gamma(concentration, 1) samples divided by their sum
+	var newGenome [8]Gene
+	for i := range newGenome {
+		newGenome[i] = Gene(GammaSample(concentration))
+	}
+
+	return NormalizeGenome(newGenome)
+}
+
+// GammaSample draws a sample from the Gamma(shape, 1) distribution using the method of Marsaglia and Tsang.
+// https://dl.acm.org/doi/10.1145/358407.358414
+func GammaSample(shape float64) float64 {
+	// Marsaglia and Tsang need shape >= 1, so boost smaller shapes and scale the sample back down
+	if shape < 1 {
+		return GammaSample(shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// NamedGenome returns one of the fixed founder strategies.
+// "cruiser" nearly always keeps its last direction (gene 0), so it travels in long straight lines.
+// "circler" nearly always turns by one step (gene 1), so it swims in tight loops.
+func NamedGenome(name string) [8]Gene {
+	var favouriteGene int
+	if name == "cruiser" {
+		favouriteGene = 0
+	} else if name == "circler" {
+		favouriteGene = 1
+	} else {
+		panic("invalid genome name inputted. should be cruiser or circler!")
+	}
+
+	// 0.72 on the favourite gene leaves 0.04 for each of the other seven
+	var newGenome [8]Gene
+	for i := range newGenome {
+		newGenome[i] = Gene(0.04)
+	}
+	newGenome[favouriteGene] = Gene(0.72)
+
+	return newGenome
+}
+
+// NormalizeGenome rescales the genes of someGenome so that they add up to 1. A genome of all zeros becomes uniform.
+func NormalizeGenome(someGenome [8]Gene) [8]Gene {
+	sum := Gene(0.0)
+	for i := range someGenome {
+		if someGenome[i] < 0 {
+			someGenome[i] = 0
+		}
+		sum += someGenome[i]
+	}
+
+	for i := range someGenome {
+		if sum == 0 {
+			someGenome[i] = Gene(0.125)
+		} else {
+			someGenome[i] /= sum
+		}
+	}
+
+	return someGenome
+}
+
+// WriteGenomesToFile writes the genome of every organism in someEcosystem to filename, one organism per line.
+// Each line is the species ("prey" or "predator") followed by its 8 genes and the 6 weights of its VerticalGenome (up, stay, down by day, then by night),
+// which is the format LoadGenomesFromFile reads.
+func WriteGenomesToFile(someEcosystem *Ecosystem, filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create genome file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curUnit := (*someEcosystem)[i][j]
+			if curUnit.prey != nil {
+				WriteGenomeLine(writer, "prey", &curUnit.prey.Organism)
+			}
+			if curUnit.predator != nil {
+				WriteGenomeLine(writer, "predator", &curUnit.predator.Organism)
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		panic("could not write genome file " + filename + ": " + err.Error())
+	}
+}
+
+// WriteGenomeLine writes the line of one organism of the given species to a genome file, see WriteGenomesToFile.
+func WriteGenomeLine(writer *bufio.Writer, species string, someOrganism *Organism) {
+	var verticalGenes []string
+	for phase := range someOrganism.verticalGenome {
+		for move := range someOrganism.verticalGenome[phase] {
+			verticalGenes = append(verticalGenes, strconv.FormatFloat(float64(someOrganism.verticalGenome[phase][move]), 'f', 6, 64))
+		}
+	}
+	fmt.Fprintln(writer, species, GenomeToString(someOrganism.genome), strings.Join(verticalGenes, " "))
+}
+
+// GenomeToString formats the genes of someGenome separated by spaces.
+func GenomeToString(someGenome [8]Gene) string {
+	genes := make([]string, len(someGenome))
+	for i := range someGenome {
+		genes[i] = strconv.FormatFloat(float64(someGenome[i]), 'f', 6, 64)
+	}
+	return strings.Join(genes, " ")
+}
+
+// CheckGenomeRule returns an error if CreateGenome can't make a genome for species with genomeRule:
+// the rule is unknown, or it is "file" and no genomes of that species were loaded with LoadGenomesFromFile.
+func CheckGenomeRule(genomeRule, species string) error {
+	if genomeRule == "file" {
+		if len(founderGenomes[species]) == 0 {
+			return fmt.Errorf("no %s genomes were loaded from %s", species, genomeFile)
+		}
+		return nil
+	}
+	if genomeRule != "uniform" && genomeRule != "dirichlet" && genomeRule != "cruiser" && genomeRule != "circler" {
+		return fmt.Errorf("unknown genomeRule %s. should be uniform, dirichlet, cruiser, circler, or file", genomeRule)
+	}
+	return nil
+}
+
+// LoadGenomesFromFile reads a genome file written by WriteGenomesToFile.
+// Blank lines and lines starting with # are skipped. Every genome is renormalised in case it was edited by hand.
+// The 6 vertical weights may be left out, as in files written before there were depth layers, which gives FounderVerticalGenome().
+// Output: a map from species to the list of genomes for that species
+func LoadGenomesFromFile(filename string) map[string][]FounderGenome {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic("could not open genome file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	genomes := make(map[string][]FounderGenome)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if (len(fields) != 9 && len(fields) != 15) || (fields[0] != "prey" && fields[0] != "predator") {
+			panic(fmt.Sprintf("%s line %d: expected prey or predator followed by 8 genes and optionally 6 vertical weights", filename, lineNumber))
+		}
+
+		genes := make([]Gene, len(fields)-1)
+		for i := range genes {
+			gene, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				panic(fmt.Sprintf("%s line %d: %s", filename, lineNumber, err.Error()))
+			}
+			genes[i] = Gene(gene)
+		}
+
+		var founder FounderGenome
+		copy(founder.genome[:], genes[:8])
+		founder.genome = NormalizeGenome(founder.genome)
+		founder.verticalGenome = FounderVerticalGenome()
+		if len(genes) == 14 {
+			for phase := range founder.verticalGenome {
+				copy(founder.verticalGenome[phase][:], genes[8+3*phase:])
+			}
+			founder.verticalGenome = NormalizeVerticalGenome(founder.verticalGenome)
+		}
+		genomes[fields[0]] = append(genomes[fields[0]], founder)
+	}
+
+	if err := scanner.Err(); err != nil {
+		panic("could not read genome file " + filename + ": " + err.Error())
+	}
+
+	return genomes
+}
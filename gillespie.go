@@ -0,0 +1,299 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+// GillespieSimulation is the continuous-time engine. instead of generations in which every organism acts exactly once, every organism has
+// its own rates, and the next event is sampled from them with the next-reaction method: each organism holds one scheduled event, drawn from an exponential
+// distribution with its total rate, and the events fire in order of time. when an event fires, its kind is chosen in proportion to the rates:
+//
+//	move       rate speed                              one step with the species' behaviour, feeding on the way, see MovePrey() and MovePredator()
+//	live       rate 1                                  one time unit of life: ageing, digesting, the basal metabolic cost and the chance of dying of old age
+//	reproduce  rate 1 once mature                      with a free neighbour (and a mate in sexual mode), see Rates()
+//
+// an organism dies as soon as its energy runs out. food grows in every empty Unit at the rate that gives the food rule's probability per time unit, see FoodRate().
+// it runs on a SparseEcosystem through the Board of the sparse backend, so every event goes through the same rules as the discrete engine,
+// and records its stats every gillespieInterval time units, so the stats file can be compared with the discrete engine's.
+type GillespieSimulation struct {
+	gen         SparseGeneration // the Board of the sparse backend. gen.curGen is the whole time unit the clock is in, for the environment fields
+	now         float64
+	events      organismQueue
+	clocks      map[*Organism]*OrganismClock
+	foodRule    string
+	nextFood    float64 // time of the next food event, which are kept out of events since they aren't tied to an organism
+	maxFoodRate float64 // FoodRate() of the Unit with the highest food probability
+}
+
+// OrganismClock is where an organism of a GillespieSimulation is, and which of its scheduled events is still valid.
+type OrganismClock struct {
+	cell       int
+	isPredator bool
+	version    int // bumped whenever the organism is rescheduled, so the older event is skipped
+
+	// the rates of the move, live and reproduce events when the pending event was drawn, which decide its kind when it fires
+	moveRate      float64
+	liveRate      float64
+	reproduceRate float64
+}
+
+// organismEvent is the next event of an organism, valid while version matches its OrganismClock.
+type organismEvent struct {
+	time     float64
+	organism *Organism
+	version  int
+}
+
+// organismQueue is a min-heap of organismEvents by time, for container/heap.
+type organismQueue []organismEvent
+
+func (queue organismQueue) Len() int            { return len(queue) }
+func (queue organismQueue) Less(a, b int) bool  { return queue[a].time < queue[b].time }
+func (queue organismQueue) Swap(a, b int)       { queue[a], queue[b] = queue[b], queue[a] }
+func (queue *organismQueue) Push(x interface{}) { *queue = append(*queue, x.(organismEvent)) }
+func (queue *organismQueue) Pop() interface{} {
+	old := *queue
+	last := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return last
+}
+
+// FoodRate() is the rate at which food grows in an empty Unit where the discrete engine grows it with the given probability per generation:
+// the Unit stays empty for a time unit with probability 1 - probability either way.
+func FoodRate(probability float64) float64 {
+	return -math.Log(1 - probability)
+}
+
+// SimulateGillespie() runs the continuous-time engine on initialEcosystem for totalTime time units, recording the stats every interval time units into allStats.
+// like SimulateSparseEcosystemEvolution() it only returns the start, every keepEvery-th recording and the last one.
+func SimulateGillespie(initialEcosystem *SparseEcosystem, totalTime, interval float64, foodRule string, keepEvery int) []*SparseEcosystem {
+	fmt.Println("SimulateGillespie is running")
+	CheckSimpleModel("gillespie simulationEngine", true)
+	if interval <= 0 {
+		panic("the gillespieInterval must be above 0")
+	}
+
+	ResetStats(0)
+	curStats.preyLedger.introduced, curStats.predLedger.introduced = initialEcosystem.SpeciesEnergy()
+	allStats = []GenerationStats{FinishSparseStats(initialEcosystem)}
+
+	sim := &GillespieSimulation{
+		gen:         SparseGeneration{eco: initialEcosystem.DeepCopy()},
+		clocks:      make(map[*Organism]*OrganismClock),
+		foodRule:    foodRule,
+		maxFoodRate: FoodRate(MaxFoodProbability(foodRule)),
+	}
+	// a newborn gets its clock as soon as it is placed
+	sim.gen.born = func(cell int, isPredator bool) {
+		if isPredator {
+			sim.AddClock(&sim.gen.eco.predators[cell].Organism, cell, true)
+		} else {
+			sim.AddClock(&sim.gen.eco.prey[cell].Organism, cell, false)
+		}
+	}
+	for _, cell := range sim.gen.eco.PreyCells() {
+		sim.AddClock(&sim.gen.eco.prey[cell].Organism, cell, false)
+	}
+	for _, cell := range sim.gen.eco.PredatorCells() {
+		sim.AddClock(&sim.gen.eco.predators[cell].Organism, cell, true)
+	}
+	sim.ScheduleFood()
+
+	kept := []*SparseEcosystem{initialEcosystem}
+	numIntervals := int(math.Round(totalTime / interval))
+	for k := 1; k <= numIntervals; k++ {
+		ResetStats(k)
+		curStats.preyLedger.startEnergy, curStats.predLedger.startEnergy = sim.gen.eco.SpeciesEnergy()
+		sim.RunUntil(float64(k) * interval)
+		allStats = append(allStats, FinishSparseStats(sim.gen.eco))
+
+		if k%keepEvery == 0 || k == numIntervals {
+			kept = append(kept, sim.gen.eco.DeepCopy())
+		}
+		if (numIntervals/10) != 0 && k%(numIntervals/10) == 0 {
+			fmt.Println("Simulation is", 100*float64(k)/float64(numIntervals), "percent complete. Time =", sim.now)
+		}
+	}
+	return kept
+}
+
+// RunUntil() fires the events in order of time until endTime.
+func (sim *GillespieSimulation) RunUntil(endTime float64) {
+	for {
+		nextTime := sim.nextFood
+		if sim.events.Len() > 0 && sim.events[0].time < nextTime {
+			nextTime = sim.events[0].time
+		}
+		if nextTime >= endTime {
+			break
+		}
+
+		sim.now = nextTime
+		sim.gen.curGen = int(sim.now)
+		if nextTime == sim.nextFood {
+			sim.GrowFood()
+			sim.ScheduleFood()
+		} else {
+			nextEvent := heap.Pop(&sim.events).(organismEvent)
+			if clock := sim.clocks[nextEvent.organism]; clock != nil && clock.version == nextEvent.version {
+				sim.Fire(nextEvent.organism, clock)
+			}
+		}
+	}
+	sim.now = endTime
+	sim.gen.curGen = int(sim.now)
+}
+
+// ScheduleFood() draws the time of the next food event. the events come at maxFoodRate from every Unit, and GrowFood() thins them down to the rate of the Unit.
+func (sim *GillespieSimulation) ScheduleFood() {
+	totalRate := sim.maxFoodRate * float64(sim.gen.eco.numRows*sim.gen.eco.numCols)
+	sim.nextFood = sim.now + rng.ExpFloat64()/totalRate
+}
+
+// GrowFood() grows food in a random Unit with probability FoodRate() of the Unit divided by maxFoodRate, if it is empty.
+func (sim *GillespieSimulation) GrowFood() {
+	eco := sim.gen.eco
+	cell := rng.Intn(eco.numRows * eco.numCols)
+	i, j := eco.Location(cell)
+	if rng.Float64()*sim.maxFoodRate < FoodRate(UnitFoodProbability(sim.foodRule, i, j, eco.numRows, eco.numCols, sim.gen.curGen)) {
+		eco.food.Set(cell, true)
+	}
+}
+
+// AddClock() gives an organism in cell its OrganismClock and its first event.
+func (sim *GillespieSimulation) AddClock(someOrganism *Organism, cell int, isPredator bool) {
+	clock := &OrganismClock{cell: cell, isPredator: isPredator}
+	sim.clocks[someOrganism] = clock
+	sim.Schedule(someOrganism, clock)
+}
+
+// Schedule() draws the next event of an organism from its total rate, replacing the one it had.
+func (sim *GillespieSimulation) Schedule(someOrganism *Organism, clock *OrganismClock) {
+	clock.version++
+	clock.moveRate, clock.liveRate, clock.reproduceRate = sim.Rates(someOrganism, clock)
+	nextTime := sim.now + rng.ExpFloat64()/(clock.moveRate+clock.liveRate+clock.reproduceRate)
+	heap.Push(&sim.events, organismEvent{time: nextTime, organism: someOrganism, version: clock.version})
+}
+
+// Rates() returns the rates of the move, live and reproduce events of an organism, see GillespieSimulation.
+// the organism can only reproduce once it is mature, like in UpdatePrey() and UpdatePredator(): its energy is above the threshold and its timeSinceReproduction,
+// which live events count up, has reached ScaledAge() of its ageThreshold. until then the reproduce rate is 0, so a newborn or a parent that just reproduced
+// waits out its ageThreshold. a mature organism tries once per time unit, as it does in every generation of the discrete engine.
+// the rates are worked out again after every event, so the organism starts trying at the live event that makes it mature.
+func (sim *GillespieSimulation) Rates(someOrganism *Organism, clock *OrganismClock) (float64, float64, float64) {
+	reproduceRate := 0.0
+	if someOrganism.IsMature(sim.gen.ThermalFactor(sim.gen.eco.Location(clock.cell))) {
+		reproduceRate = 1
+	}
+	return float64(someOrganism.traits.speed), 1, reproduceRate
+}
+
+// Fire() carries out the event an organism has due: one of its events, chosen in proportion to the rates. if the organism is still alive
+// it starves when its energy has run out, and otherwise its next event is scheduled.
+func (sim *GillespieSimulation) Fire(someOrganism *Organism, clock *OrganismClock) {
+	eco := sim.gen.eco
+	var currentPrey *Prey
+	var shark *Predator
+	if clock.isPredator {
+		shark = eco.predators[clock.cell]
+	} else {
+		currentPrey = eco.prey[clock.cell]
+	}
+	// a prey that was eaten is gone from its cell, and is forgotten the first time its event comes up
+	if !sim.IsInCell(someOrganism, clock) {
+		delete(sim.clocks, someOrganism)
+		return
+	}
+
+	r := rng.Float64() * (clock.moveRate + clock.liveRate + clock.reproduceRate)
+	if r < clock.moveRate {
+		sim.Move(clock)
+	} else if r < clock.moveRate+clock.liveRate {
+		sim.Live(currentPrey, shark, clock)
+	} else {
+		sim.Reproduce(clock)
+	}
+
+	if sim.IsInCell(someOrganism, clock) {
+		sim.StarveIfEmpty(currentPrey, shark, clock)
+	}
+	if sim.IsInCell(someOrganism, clock) {
+		sim.Schedule(someOrganism, clock)
+	} else {
+		delete(sim.clocks, someOrganism)
+	}
+}
+
+// IsInCell() says whether someOrganism is still in the cell of its clock. the rules take the organisms that die off the board, so one that isn't is dead.
+func (sim *GillespieSimulation) IsInCell(someOrganism *Organism, clock *OrganismClock) bool {
+	if clock.isPredator {
+		shark := sim.gen.eco.predators[clock.cell]
+		return shark != nil && &shark.Organism == someOrganism
+	}
+	currentPrey := sim.gen.eco.prey[clock.cell]
+	return currentPrey != nil && &currentPrey.Organism == someOrganism
+}
+
+// Site() is the LatticeSite of the organism of clock.
+func (sim *GillespieSimulation) Site(clock *OrganismClock) *LatticeSite {
+	i, j := sim.gen.eco.Location(clock.cell)
+	return &LatticeSite{board: &sim.gen, i: i, j: j, isPredator: clock.isPredator}
+}
+
+// Move() moves the organism one step, see MovePrey() and MovePredator(). a prey that starves on the way is taken off the board by MovePrey().
+func (sim *GillespieSimulation) Move(clock *OrganismClock) {
+	site := sim.Site(clock)
+	site.Step()
+	clock.cell = sim.gen.eco.Cell(site.i, site.j)
+}
+
+// Live() is one time unit of the life of the organism: it may die (see PreyDies() and Dies()), and otherwise it ages,
+// a predator digests, and it pays its basal metabolic cost.
+func (sim *GillespieSimulation) Live(currentPrey *Prey, shark *Predator, clock *OrganismClock) {
+	site := sim.Site(clock)
+	thermalFactor := site.ThermalFactor()
+	if currentPrey != nil {
+		if PreyDies(site, currentPrey) {
+			return
+		}
+		UpdateAgePrey(currentPrey)
+		metabolicCost := ScaledCost(currentPrey.traits.metabolism, thermalFactor)
+		currentPrey.energy -= metabolicCost
+		curStats.preyLedger.basal += metabolicCost
+	} else {
+		if shark.Dies(site) {
+			return
+		}
+		shark.Digest()
+		shark.UpdateAge()
+		metabolicCost := ScaledCost(shark.traits.metabolism, thermalFactor)
+		shark.energy -= metabolicCost
+		curStats.predLedger.basal += metabolicCost
+	}
+}
+
+// StarveIfEmpty() removes the organism if it has run out of energy.
+func (sim *GillespieSimulation) StarveIfEmpty(currentPrey *Prey, shark *Predator, clock *OrganismClock) {
+	if currentPrey != nil && currentPrey.energy <= 0 {
+		StarvePrey(sim.Site(clock), currentPrey)
+	} else if shark != nil && shark.energy <= 0 {
+		shark.Starve(sim.Site(clock))
+	}
+}
+
+// Reproduce() places a newborn in a free neighbour of the organism, see BreedPrey() and Breed(). the newborn gets its clock from gen.born.
+// in sexual mode it needs a mate among the neighbours, whose events are rescheduled since it gave energy to the newborn.
+func (sim *GillespieSimulation) Reproduce(clock *OrganismClock) {
+	if mate := sim.Site(clock).Breed(); mate != nil {
+		sim.Reschedule(mate)
+	}
+}
+
+// Reschedule() draws a new event for an organism whose rates were changed by another organism.
+func (sim *GillespieSimulation) Reschedule(someOrganism *Organism) {
+	if clock := sim.clocks[someOrganism]; clock != nil {
+		sim.Schedule(someOrganism, clock)
+	}
+}
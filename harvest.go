@@ -0,0 +1,94 @@
+package main
+
+// HarvestPolicy says how fishing removes organisms from the Ecosystem every generation. rule picks the policy:
+// "none" doesn't fish, "quota" catches up to quota organisms, "effort" catches each organism present with probability effort,
+// and "selective" is "effort" restricted to organisms at least minAge old with at least minEnergy energy (our stand-in for body size).
+// Only the species switched on by targetPrey and targetPredators are caught, only inside fishingGrounds (everywhere if it's empty),
+// and never inside a marine protected area (see protectedAreas).
+type HarvestPolicy struct {
+	rule            string
+	targetPrey      bool
+	targetPredators bool
+	quota           int
+	effort          float64
+	minAge          int
+	minEnergy       int
+	fishingGrounds  []Region
+}
+
+// Harvest() applies harvestPolicy once to the layers of the ocean (just one for a flat ocean) and records the yield in curStats.
+// the quota and the effort cover all the layers together, so fishing a deeper ocean doesn't catch more.
+func Harvest(layers ...*Ecosystem) {
+	if harvestPolicy.rule == "none" {
+		return
+	}
+
+	var catchable []*Unit
+	for _, someEcosystem := range layers {
+		catchable = append(catchable, CatchableUnits(someEcosystem)...)
+	}
+
+	if harvestPolicy.rule == "quota" {
+		rng.Shuffle(len(catchable), func(a, b int) {
+			catchable[a], catchable[b] = catchable[b], catchable[a]
+		})
+		for k := 0; k < harvestPolicy.quota && k < len(catchable); k++ {
+			CatchOrganism(catchable[k])
+		}
+	} else if harvestPolicy.rule == "effort" || harvestPolicy.rule == "selective" {
+		for _, curUnit := range catchable {
+			if rng.Float64() < harvestPolicy.effort {
+				CatchOrganism(curUnit)
+			}
+		}
+	} else {
+		panic("invalid harvest rule string inputted. should be none, quota, effort, or selective!")
+	}
+}
+
+// CatchableUnits() lists the Units that hold an organism harvestPolicy is allowed to catch.
+func CatchableUnits(someEcosystem *Ecosystem) []*Unit {
+	var catchable []*Unit
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if InAnyRegion(protectedAreas, i, j) {
+				continue
+			}
+			if len(harvestPolicy.fishingGrounds) != 0 && !InAnyRegion(harvestPolicy.fishingGrounds, i, j) {
+				continue
+			}
+
+			curUnit := (*someEcosystem)[i][j]
+			if harvestPolicy.targetPrey && curUnit.prey != nil && IsCatchable(&curUnit.prey.Organism) {
+				catchable = append(catchable, curUnit)
+			} else if harvestPolicy.targetPredators && curUnit.predator != nil && IsCatchable(&curUnit.predator.Organism) {
+				catchable = append(catchable, curUnit)
+			}
+		}
+	}
+	return catchable
+}
+
+// IsCatchable() checks the age and size limits of the "selective" policy. every organism is catchable under the other policies.
+func IsCatchable(someOrganism *Organism) bool {
+	if harvestPolicy.rule != "selective" {
+		return true
+	}
+	return someOrganism.age >= harvestPolicy.minAge && someOrganism.energy >= harvestPolicy.minEnergy
+}
+
+// CatchOrganism() removes the targeted organism of curUnit and adds it to the yield of the current generation.
+// a newborn predator can share a Unit with a prey, so the limits are checked again for whichever organism is caught.
+func CatchOrganism(curUnit *Unit) {
+	if harvestPolicy.targetPrey && curUnit.prey != nil && IsCatchable(&curUnit.prey.Organism) {
+		curStats.preyHarvested++
+		curStats.preyYieldEnergy += curUnit.prey.energy
+		curStats.preyLedger.lostAtDeath += curUnit.prey.energy
+		curUnit.prey = nil
+	} else if harvestPolicy.targetPredators && curUnit.predator != nil && IsCatchable(&curUnit.predator.Organism) {
+		curStats.predHarvested++
+		curStats.predYieldEnergy += curUnit.predator.energy
+		curStats.predLedger.lostAtDeath += curUnit.predator.energy
+		curUnit.predator = nil
+	}
+}
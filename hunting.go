@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math"
+)
+
+// Hunt() is the "hunting" behaviour of a predator. The shark looks for the nearest prey within its visionRadius trait.
+// A shark that can't eat (see CanEat()) doesn't hunt, and prey inside a refuge are out of reach. If it sees one and the next cell towards it is free, it steps there, paying chaseEnergyCostPredator for every cell between them. Stepping onto a prey is a pounce, which
+// succeeds with probability captureProbability; a failed pounce leaves the shark where it was. If no prey is in sight the shark falls back to its MovementPolicy, movementPredator.
+// The shark hunts by sight, so in an Ocean both its visionRadius and captureProbability are scaled by the light it hunts in, curLight.
+// Output: the same values as MovementPolicy.Move(): deltaRow, deltaCol, newDirection, geneIndex, newI, newJ
+func (shark *Predator) Hunt(board Board, i, j int) (int, int, int, int, int, int) {
+	if !shark.CanEat() {
+		return movementPredator.Move(board, shark, i, j)
+	}
+
+	visionRadius := int(math.Round(float64(shark.traits.visionRadius) * curLight))
+	targetRow, targetCol, distance := FindNearest(board, i, j, visionRadius, HasExposedPrey)
+	if distance == 0 {
+		return movementPredator.Move(board, shark, i, j)
+	}
+
+	deltaRow, deltaCol := StepTowards(targetRow, targetCol)
+	newI := GetIndex(i, deltaRow, board.CountRows())
+	newJ := GetIndex(j, deltaCol, board.CountCols())
+
+	// another shark is in the way, so wander instead
+	if !shark.isFreeUnit(board, newI, newJ) {
+		return movementPredator.Move(board, shark, i, j)
+	}
+
+	// the chase costs more the further away the prey is. a blocked shark never chased, so it only pays once it can step
+	shark.energy -= chaseEnergyCostPredator * distance
+	curStats.predLedger.moving += chaseEnergyCostPredator * distance
+
+	// pouncing on a prey can fail, in which case the shark stays put and the prey survives
+	if HasPrey(board, newI, newJ) && rng.Float64() >= captureProbability*curLight {
+		return 0, 0, shark.lastDirection, 0, i, j
+	}
+
+	newDirection := DirectionOfDelta(deltaRow, deltaCol)
+	geneIndex := (newDirection - shark.lastDirection + 8) % 8
+	return deltaRow, deltaCol, newDirection, geneIndex, newI, newJ
+}
+
+// HuntFood() is the "hunting" behaviour of a prey. It looks for the nearest food it will eat (see WillEat()) within its visionRadius trait and steps one cell towards it,
+// paying chaseEnergyCostPrey for every cell between them once the step is known to be possible. Plankton can't escape, so there's no capture roll. If no food is in sight, or the step is blocked,
+// the prey falls back to its MovementPolicy, movementPrey.
+// Output: the same values as MovementPolicy.Move(): deltaRow, deltaCol, newDirection, geneIndex, newI, newJ
+func HuntFood(board Board, currentPrey *Prey, i, j int) (int, int, int, int, int, int) {
+	targetRow, targetCol, distance := FindNearest(board, i, j, currentPrey.traits.visionRadius, func(board Board, i, j int) bool {
+		return currentPrey.WillEat(board.FoodAt(i, j))
+	})
+	if distance == 0 {
+		return movementPrey.Move(board, currentPrey, i, j)
+	}
+
+	deltaRow, deltaCol := StepTowards(targetRow, targetCol)
+	newI := GetIndex(i, deltaRow, board.CountRows())
+	newJ := GetIndex(j, deltaCol, board.CountCols())
+
+	if !currentPrey.CanMoveTo(board, newI, newJ) {
+		return movementPrey.Move(board, currentPrey, i, j)
+	}
+
+	currentPrey.energy -= chaseEnergyCostPrey * distance
+	curStats.preyLedger.moving += chaseEnergyCostPrey * distance
+
+	newDirection := DirectionOfDelta(deltaRow, deltaCol)
+	geneIndex := (newDirection - currentPrey.lastDirection + 8) % 8
+	return deltaRow, deltaCol, newDirection, geneIndex, newI, newJ
+}
+
+// FindNearest() searches the Units around i, j in rings of growing Chebyshev distance, wrapping around the edges, up to radius.
+// Input: the Board, the indices i, j, the search radius and isTarget, which says whether a Unit holds what we are looking for
+// Output: the offset (deltaRow, deltaCol) to the nearest target and its distance. distance is 0 if nothing was found.
+// Ties are broken at random so the hunter doesn't always favour the same side.
+func FindNearest(board Board, i, j, radius int, isTarget func(Board, int, int) bool) (int, int, int) {
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+
+	for distance := 1; distance <= radius; distance++ {
+		var targets []OrderedPair
+		for deltaRow := -distance; deltaRow <= distance; deltaRow++ {
+			for deltaCol := -distance; deltaCol <= distance; deltaCol++ {
+				// only look at the ring, the inside was searched already
+				if Abs(deltaRow) != distance && Abs(deltaCol) != distance {
+					continue
+				}
+				if isTarget(board, GetIndex(i, deltaRow, numRows), GetIndex(j, deltaCol, numCols)) {
+					targets = append(targets, OrderedPair{deltaRow, deltaCol})
+				}
+			}
+		}
+
+		if len(targets) != 0 {
+			chosen := targets[rng.Intn(len(targets))]
+			return chosen.row, chosen.col, distance
+		}
+	}
+
+	return 0, 0, 0
+}
+
+// StepTowards() returns the single-cell move (each delta in -1, 0 or 1) that gets closest to the offset deltaRow, deltaCol.
+func StepTowards(deltaRow, deltaCol int) (int, int) {
+	return Sign(deltaRow), Sign(deltaCol)
+}
+
+// DirectionOfDelta() returns the direction (the key in deltas) that moves by deltaRow, deltaCol. it panics if there is none, e.g. for 0, 0
+func DirectionOfDelta(deltaRow, deltaCol int) int {
+	for direction, moveDeltas := range deltas {
+		if moveDeltas.row == deltaRow && moveDeltas.col == deltaCol {
+			return direction
+		}
+	}
+	panic("no direction moves by the given deltas")
+}
+
+// HasPrey() says whether Unit i, j holds a prey
+func HasPrey(board Board, i, j int) bool {
+	return board.PreyAt(i, j) != nil
+}
+
+// HasExposedPrey() says whether Unit i, j holds a prey that isn't sheltered in a refuge
+func HasExposedPrey(board Board, i, j int) bool {
+	return board.PreyAt(i, j) != nil && board.RefugeAt(i, j) == nil
+}
+
+// Abs() returns the absolute value of an int
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Sign() returns -1, 0 or 1 depending on the sign of x
+func Sign(x int) int {
+	if x < 0 {
+		return -1
+	} else if x > 0 {
+		return 1
+	}
+	return 0
+}
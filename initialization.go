@@ -1,35 +1,48 @@
 package main
 
 import (
-	"math/rand"
+	"fmt"
 )
 
 // InitializePreyAndPredator
 // Randomly generate numPrey and numPred predators in the initialEcosystem.
 // Functions written by Akshat
-func InitializePreyAndPredator(numRows, numCols, numPrey, numPred int, newEco *Ecosystem) {
+// the predators go to distinct Units outside the refuges, and the prey to distinct Units without a predator where the refuge has room.
+// each species draws its Units without replacement from the eligible ones, so it returns an error instead of searching forever when there aren't enough.
+func InitializePreyAndPredator(numRows, numCols, numPrey, numPred int, newEco *Ecosystem) error {
 	// Akshat wrote these: Randomly initialize the prey and predators
-	count_Prey := 0
 	count_Pred := 0
-
-	for count_Pred < numPred {
-		i := rand.Intn(numRows)
-		j := rand.Intn(numCols)
-		if (*newEco)[i][j].predator == nil {
-			(*newEco)[i][j].predator = CreatePredator()
+	for _, cell := range rng.Perm(numRows * numCols) {
+		if count_Pred == numPred {
+			break
+		}
+		unit := (*newEco)[cell/numCols][cell%numCols]
+		if unit.refuge == nil {
+			unit.predator = CreatePredator()
 			count_Pred += 1
 		}
-
+	}
+	if count_Pred < numPred {
+		return fmt.Errorf("there are only %d Units outside the refuges for %d predators", count_Pred, numPred)
 	}
 
-	for count_Prey < numPrey {
-		i := rand.Intn(numRows)
-		j := rand.Intn(numCols)
-		if (*newEco)[i][j].prey == nil && (*newEco)[i][j].predator == nil {
-			(*newEco)[i][j].prey = CreatePrey()
+	// a refuge fills up as prey are placed in it, so its room is checked as they go
+	count_Prey := 0
+	for _, cell := range rng.Perm(numRows * numCols) {
+		if count_Prey == numPrey {
+			break
+		}
+		i, j := cell/numCols, cell%numCols
+		unit := (*newEco)[i][j]
+		if unit.predator == nil && unit.refuge.HasRoom(newEco, nil) {
+			unit.prey = CreatePrey()
 			count_Prey += 1
 		}
 	}
+	if count_Prey < numPrey {
+		return fmt.Errorf("there is only room for %d of the %d prey in Units without a predator", count_Prey, numPrey)
+	}
+	return nil
 }
 
 // CreatePrey initializes the Prey object
@@ -37,10 +50,11 @@ func CreatePrey() *Prey {
 	var newPrey Prey
 	newPrey.Organism.age = 0
 	newPrey.Organism.energy = 50
-	newPrey.Organism.age = 0
-	newPrey.Organism.genome = CreateGenome()
+	newPrey.Organism.timeSinceReproduction = 0
+	newPrey.Organism.genome, newPrey.Organism.verticalGenome = CreateGenome(genomeRulePrey, "prey")
 	newPrey.Organism.lastGenUpdated = 0
 	newPrey.Organism.lastDirection = 0
+	newPrey.Organism.traits = FounderTraitsPrey()
 	return &newPrey
 }
 
@@ -49,25 +63,41 @@ func CreatePredator() *Predator {
 	var newPredator Predator
 	newPredator.Organism.age = 0
 	newPredator.Organism.energy = 50
-	newPredator.Organism.age = 0
-	newPredator.Organism.genome = CreateGenome()
+	newPredator.Organism.timeSinceReproduction = 0
+	newPredator.Organism.genome, newPredator.Organism.verticalGenome = CreateGenome(genomeRulePredator, "predator")
 	newPredator.Organism.lastGenUpdated = 0
 	newPredator.Organism.lastDirection = 0
+	newPredator.Organism.traits = FounderTraitsPredator()
 	return &newPredator
 
 }
 
-// CreateGenome creates the first version of the genome and returns it.
-func CreateGenome() [8]Gene {
+// CreateGenome creates the first version of the genome and the VerticalGenome for a founder of the given species and returns them.
+// genomeRule decides how the founding population starts: "uniform" gives every gene 0.125, "dirichlet" draws a random genome with concentration dirichletConcentration, "cruiser" and "circler" are fixed named strategies, and "file" picks one of the genomes loaded with LoadGenomesFromFile.
+// Only "file" can give an evolved VerticalGenome, every other rule starts from FounderVerticalGenome().
+func CreateGenome(genomeRule, species string) ([8]Gene, VerticalGenome) {
 	var newGenome [8]Gene
-	for i := range newGenome {
-		newGenome[i] = Gene(0.125)
+	if genomeRule == "uniform" {
+		for i := range newGenome {
+			newGenome[i] = Gene(0.125)
+		}
+	} else if genomeRule == "dirichlet" {
+		newGenome = DirichletGenome(dirichletConcentration)
+	} else if genomeRule == "cruiser" || genomeRule == "circler" {
+		newGenome = NamedGenome(genomeRule)
+	} else if genomeRule == "file" {
+		if len(founderGenomes[species]) == 0 {
+			panic("no " + species + " genomes were loaded from " + genomeFile)
+		}
+		founder := founderGenomes[species][rng.Intn(len(founderGenomes[species]))]
+		return founder.genome, founder.verticalGenome
+	} else {
+		panic("invalid genomeRule string inputted. should be uniform, dirichlet, cruiser, circler, or file!")
 	}
-	return newGenome
+	return newGenome, FounderVerticalGenome()
 }
 
 func InitializeEcosystem(numRows, numCols, numPrey, numPred int) Ecosystem {
-
 	// initialize newEco, which has numRows rows. the outer dimension
 	newEco := make(Ecosystem, numRows)
 	for i := 0; i < numRows; i++ {
@@ -78,14 +108,25 @@ func InitializeEcosystem(numRows, numCols, numPrey, numPred int) Ecosystem {
 			newEco[i][j] = new(Unit)
 
 			// generate food randomly. 50% chance of generating food at every location in initial system
-			randomFood := rand.Float64()
-			if randomFood > 0.90 {
-				newEco[i][j].food.isPresent = true
+			randomFood := rng.Float64()
+			if foodType := RandomFoodType(i, j); randomFood > 0.90 && foodType != -1 {
+				newEco[i][j].food = Food{isPresent: true, foodType: foodType}
 			}
 		}
 	}
 
-	InitializePreyAndPredator(numRows, numCols, numPrey, numPred, &newEco)
+	UpdateEnvironment(&newEco, 0)
+
+	// the refuges have to be known before the predators are placed, since they can't start in one
+	MarkRefuges(&newEco)
+
+	if err := InitializePreyAndPredator(numRows, numCols, numPrey, numPred, &newEco); err != nil {
+		panic(err.Error())
+	}
+
+	if diseaseEnabled {
+		InfectRandomOrganisms(&newEco, numInitiallyInfected)
+	}
 
 	return newEco
 }
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Intervention is one scripted event of an experiment, e.g. an oil spill or the release of new predators.
+// It fires once: at the given generation, or (if condition isn't "") at the first generation where the condition holds.
+// conditions are "preyBelow", "preyAbove", "predBelow" and "predAbove", compared against threshold.
+// actions and their arguments are:
+//
+//	killRegion minRow minCol maxRow maxCol           kills every prey and predator and removes the food in the rectangle
+//	addPrey count [genomeRule]                       adds count new prey at random free Units (see CreateGenome for the genome rules)
+//	addPredators count [genomeRule]                  adds count new predators the same way
+//	setParameter name value                          changes one of the global parameters, see SetParameter()
+//	setFoodRule foodRule                             switches the food rule for the rest of the simulation
+//	foodBloom minRow minCol maxRow maxCol probability  grows food in each empty Unit of the rectangle with the given probability
+type Intervention struct {
+	generation int
+	condition  string
+	threshold  int
+	action     string
+	args       []string
+	fired      bool
+}
+
+// LoadInterventionsFromFile() reads an intervention schedule. every line is a trigger followed by an action and its arguments:
+//
+//	at 500 killRegion 10 10 20 20
+//	when preyBelow 50 addPrey 30 cruiser
+//
+// Blank lines and lines starting with # are skipped. a malformed line panics, so a typo doesn't silently skip an intervention.
+func LoadInterventionsFromFile(filename string) []Intervention {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic("could not open intervention file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	var schedule []Intervention
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var newIntervention Intervention
+		var rest []string
+		if fields[0] == "at" && len(fields) >= 3 {
+			newIntervention.generation = ParseIntArgument(fields[1], filename, lineNumber)
+			rest = fields[2:]
+		} else if fields[0] == "when" && len(fields) >= 4 {
+			newIntervention.condition = fields[1]
+			newIntervention.threshold = ParseIntArgument(fields[2], filename, lineNumber)
+			rest = fields[3:]
+			if newIntervention.condition != "preyBelow" && newIntervention.condition != "preyAbove" && newIntervention.condition != "predBelow" && newIntervention.condition != "predAbove" {
+				panic(fmt.Sprintf("%s line %d: unknown condition %s", filename, lineNumber, newIntervention.condition))
+			}
+		} else {
+			panic(fmt.Sprintf("%s line %d: expected \"at generation\" or \"when condition threshold\" followed by an action", filename, lineNumber))
+		}
+
+		newIntervention.action = rest[0]
+		newIntervention.args = rest[1:]
+		CheckInterventionArguments(newIntervention, filename, lineNumber)
+		schedule = append(schedule, newIntervention)
+	}
+
+	if err := scanner.Err(); err != nil {
+		panic("could not read intervention file " + filename + ": " + err.Error())
+	}
+
+	return schedule
+}
+
+// CheckInterventionArguments() panics if the action of someIntervention is unknown, has the wrong number of arguments,
+// or has an argument that doesn't parse, so Apply() can trust its arguments. that covers the numbers, the genome rule of addPrey and addPredators,
+// the name and value of setParameter, and the food rule of setFoodRule. it doesn't change anything, so the "file" genome rule is checked
+// once main has loaded genomeFile, see GenomeFileSpecies().
+func CheckInterventionArguments(someIntervention Intervention, filename string, lineNumber int) {
+	args := someIntervention.args
+	var ok bool
+	var numericArgs []string
+	if someIntervention.action == "killRegion" {
+		ok = len(args) == 4
+		numericArgs = args
+	} else if someIntervention.action == "addPrey" || someIntervention.action == "addPredators" {
+		ok = len(args) == 1 || len(args) == 2
+		if ok {
+			numericArgs = args[:1]
+		}
+		if len(args) == 2 {
+			species := "prey"
+			if someIntervention.action == "addPredators" {
+				species = "predator"
+			}
+			if args[1] != "file" {
+				if err := CheckGenomeRule(args[1], species); err != nil {
+					panic(fmt.Sprintf("%s line %d: %s", filename, lineNumber, err.Error()))
+				}
+			}
+		}
+	} else if someIntervention.action == "setParameter" {
+		ok = len(args) == 2
+		if ok {
+			if err := CheckParameter(args[0], args[1]); err != nil {
+				panic(fmt.Sprintf("%s line %d: %s", filename, lineNumber, err.Error()))
+			}
+		}
+	} else if someIntervention.action == "setFoodRule" {
+		ok = len(args) == 1
+		if ok {
+			// MaxFoodProbability() panics on an unknown food rule
+			MaxFoodProbability(args[0])
+		}
+	} else if someIntervention.action == "foodBloom" {
+		ok = len(args) == 5
+		if ok {
+			numericArgs = args[:4]
+			if _, err := strconv.ParseFloat(args[4], 64); err != nil {
+				panic(fmt.Sprintf("%s line %d: %s", filename, lineNumber, err.Error()))
+			}
+		}
+	} else {
+		panic(fmt.Sprintf("%s line %d: unknown action %s", filename, lineNumber, someIntervention.action))
+	}
+
+	if !ok {
+		panic(fmt.Sprintf("%s line %d: wrong number of arguments for %s", filename, lineNumber, someIntervention.action))
+	}
+	for _, field := range numericArgs {
+		ParseIntArgument(field, filename, lineNumber)
+	}
+}
+
+// GenomeFileSpecies() returns the species ("prey" or "predator") that an action of schedule adds with the "file" genome rule,
+// so main can load their founders from genomeFile before the simulation starts.
+func GenomeFileSpecies(schedule []Intervention) []string {
+	var species []string
+	for _, someIntervention := range schedule {
+		if len(someIntervention.args) != 2 || someIntervention.args[1] != "file" {
+			continue
+		}
+		if someIntervention.action == "addPrey" {
+			species = append(species, "prey")
+		} else if someIntervention.action == "addPredators" {
+			species = append(species, "predator")
+		}
+	}
+	return species
+}
+
+// ParseIntArgument() parses an integer from an intervention file and panics with the line number if it can't.
+func ParseIntArgument(field, filename string, lineNumber int) int {
+	value, err := strconv.Atoi(field)
+	if err != nil {
+		panic(fmt.Sprintf("%s line %d: %s", filename, lineNumber, err.Error()))
+	}
+	return value
+}
+
+// ApplyInterventions() fires every intervention of the schedule that is due in generation curGen, in the order of the file.
+// Input: the Ecosystem at the end of generation curGen (changed in place), the schedule, and the food rule in use
+// Output: the food rule to use from now on, which is only different after a setFoodRule action
+func ApplyInterventions(someEcosystem *Ecosystem, schedule []Intervention, curGen int, foodRule string) string {
+	for k := range schedule {
+		someIntervention := &schedule[k]
+		if someIntervention.fired || !someIntervention.IsDue(someEcosystem, curGen) {
+			continue
+		}
+
+		someIntervention.fired = true
+		fmt.Println("Generation", curGen, "intervention:", someIntervention.action, strings.Join(someIntervention.args, " "))
+		foodRule = someIntervention.Apply(someEcosystem, foodRule)
+	}
+	return foodRule
+}
+
+// IsDue() checks whether someIntervention should fire in generation curGen.
+func (someIntervention *Intervention) IsDue(someEcosystem *Ecosystem, curGen int) bool {
+	if someIntervention.condition == "" {
+		return curGen == someIntervention.generation
+	}
+
+	countPrey, countPred := CountOrganisms(someEcosystem)
+	if someIntervention.condition == "preyBelow" {
+		return countPrey < someIntervention.threshold
+	} else if someIntervention.condition == "preyAbove" {
+		return countPrey > someIntervention.threshold
+	} else if someIntervention.condition == "predBelow" {
+		return countPred < someIntervention.threshold
+	}
+	return countPred > someIntervention.threshold
+}
+
+// Apply() carries out the action of someIntervention on someEcosystem and returns the food rule to use from now on.
+// the arguments were checked by CheckInterventionArguments() when the schedule was loaded.
+func (someIntervention *Intervention) Apply(someEcosystem *Ecosystem, foodRule string) string {
+	args := someIntervention.args
+	if someIntervention.action == "killRegion" {
+		KillRegion(someEcosystem, ParseRectangle(args[0:4]))
+	} else if someIntervention.action == "addPrey" || someIntervention.action == "addPredators" {
+		genomeRule := "uniform"
+		if len(args) == 2 {
+			genomeRule = args[1]
+		}
+		count, _ := strconv.Atoi(args[0])
+		AddOrganisms(someEcosystem, count, someIntervention.action == "addPredators", genomeRule)
+	} else if someIntervention.action == "setParameter" {
+		SetParameter(args[0], args[1])
+	} else if someIntervention.action == "setFoodRule" {
+		foodRule = args[0]
+	} else if someIntervention.action == "foodBloom" {
+		probability, _ := strconv.ParseFloat(args[4], 64)
+		FoodBloom(someEcosystem, ParseRectangle(args[0:4]), probability)
+	}
+	return foodRule
+}
+
+// ParseRectangle() turns the four checked arguments minRow minCol maxRow maxCol into a Rectangle.
+func ParseRectangle(args []string) Rectangle {
+	var corners [4]int
+	for k := range corners {
+		corners[k], _ = strconv.Atoi(args[k])
+	}
+	return Rectangle{minRow: corners[0], minCol: corners[1], maxRow: corners[2], maxCol: corners[3]}
+}
+
+// KillRegion() removes every prey, predator and food inside someRegion, e.g. an oil spill.
+func KillRegion(someEcosystem *Ecosystem, someRegion Region) {
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if !someRegion.Contains(i, j) {
+				continue
+			}
+			curUnit := (*someEcosystem)[i][j]
+			if curUnit.prey != nil {
+				curStats.preyKilledByIntervention++
+				curStats.preyLedger.lostAtDeath += curUnit.prey.energy
+				DepositCarcass(curUnit, curUnit.prey.energy)
+				curUnit.prey = nil
+			}
+			if curUnit.predator != nil {
+				curStats.predKilledByIntervention++
+				curStats.predLedger.lostAtDeath += curUnit.predator.energy
+				DepositCarcass(curUnit, curUnit.predator.energy)
+				curUnit.predator = nil
+			}
+			curUnit.food.isPresent = false
+		}
+	}
+}
+
+// AddOrganisms() places count new prey (or predators, if isPredator) at random Units that hold neither, with genomes made by genomeRule.
+// predators aren't placed in refuges, and prey aren't placed in full ones. it stops early if the Ecosystem is full.
+func AddOrganisms(someEcosystem *Ecosystem, count int, isPredator bool, genomeRule string) {
+	var freeUnits []*Unit
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].prey == nil && (*someEcosystem)[i][j].predator == nil {
+				freeUnits = append(freeUnits, (*someEcosystem)[i][j])
+			}
+		}
+	}
+	rng.Shuffle(len(freeUnits), func(a, b int) {
+		freeUnits[a], freeUnits[b] = freeUnits[b], freeUnits[a]
+	})
+
+	numAdded := 0
+	for k := 0; numAdded < count && k < len(freeUnits); k++ {
+		if isPredator && freeUnits[k].refuge != nil || !isPredator && !freeUnits[k].refuge.HasRoom(someEcosystem, nil) {
+			continue
+		}
+		numAdded++
+
+		if isPredator {
+			newPredator := CreatePredator()
+			newPredator.genome, newPredator.verticalGenome = CreateGenome(genomeRule, "predator")
+			freeUnits[k].predator = newPredator
+			curStats.predLedger.introduced += newPredator.energy
+		} else {
+			newPrey := CreatePrey()
+			newPrey.genome, newPrey.verticalGenome = CreateGenome(genomeRule, "prey")
+			freeUnits[k].prey = newPrey
+			curStats.preyLedger.introduced += newPrey.energy
+		}
+	}
+}
+
+// FoodBloom() grows food in every empty Unit of someRegion with the given probability.
+func FoodBloom(someEcosystem *Ecosystem, someRegion Region, probability float64) {
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if someRegion.Contains(i, j) && rng.Float64() < probability {
+				// the bloom only grows the food types that may grow there
+				if foodType := RandomFoodType(i, j); foodType != -1 {
+					(*someEcosystem)[i][j].food = Food{isPresent: true, foodType: foodType}
+				}
+			}
+		}
+	}
+}
+
+// CountOrganisms() returns the number of prey and the number of predators in someEcosystem.
+func CountOrganisms(someEcosystem *Ecosystem) (int, int) {
+	countPrey, countPred := 0, 0
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].prey != nil {
+				countPrey++
+			}
+			if (*someEcosystem)[i][j].predator != nil {
+				countPred++
+			}
+		}
+	}
+	return countPrey, countPred
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/csv"
+	"log"
+	"os"
+	"strconv"
+)
+
+// EnergyLedger is the energy bookkeeping of one species over one generation. Every update function that changes the energy of an organism
+// records the change here, at the point where it makes it. FinishStats() then compares the books with the energy the species actually holds,
+// so an update that changes an energy without recording it (or records a change that never reaches the organism) shows up as an imbalance.
+type EnergyLedger struct {
+	startEnergy int // held by the species after the previous generation
+	ingested    int // gained by eating plankton or prey, see EnergyTransferred()
+	moving      int // spent on movement (energyCosts) and on chases. negative if energyCosts pays organisms to turn
+	basal       int // spent on basal metabolism, the metabolism trait
+	mating      int // spent on mating costs
+	disease     int // drained by infections
+	toOffspring int // passed from parents to newborns. it stays inside the species, so it doesn't change the balance
+	lostAtDeath int // held by organisms when they starved, died, were eaten, caught or removed. can be negative for starved organisms
+	introduced  int // held by organisms that came from outside, e.g. the initial population or an intervention
+	emigrated   int // held by organisms that left for another patch, see Patch
+	immigrated  int // held by organisms that arrived from another patch
+	endEnergy   int // held by the species at the end of the generation
+}
+
+// ledgerTolerance is the largest imbalance CheckLedgers() lets through without a warning. energies are ints, so the books should balance exactly
+var ledgerTolerance int = 0
+
+// Imbalance() is how far the energy the species ended with is from what the books say it should hold. 0 means the books balance.
+func (ledger EnergyLedger) Imbalance() int {
+	expected := ledger.startEnergy + ledger.ingested + ledger.introduced + ledger.immigrated - ledger.emigrated -
+		ledger.moving - ledger.basal - ledger.mating - ledger.disease - ledger.lostAtDeath
+	return ledger.endEnergy - expected
+}
+
+// SpeciesEnergy() returns the total energy held by the prey and by the predators of someEcosystem.
+func SpeciesEnergy(someEcosystem *Ecosystem) (int, int) {
+	preyEnergy, predEnergy := 0, 0
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].prey != nil {
+				preyEnergy += (*someEcosystem)[i][j].prey.energy
+			}
+			if (*someEcosystem)[i][j].predator != nil {
+				predEnergy += (*someEcosystem)[i][j].predator.energy
+			}
+		}
+	}
+	return preyEnergy, predEnergy
+}
+
+// CheckLedgers() logs a warning for every species whose books don't balance in stats.
+// Output: true if both ledgers balance
+func CheckLedgers(stats GenerationStats) bool {
+	balanced := true
+	if imbalance := stats.preyLedger.Imbalance(); Abs(imbalance) > ledgerTolerance {
+		log.Printf("energy ledger of the prey doesn't balance in generation %d: off by %d\n", stats.generation, imbalance)
+		balanced = false
+	}
+	if imbalance := stats.predLedger.Imbalance(); Abs(imbalance) > ledgerTolerance {
+		log.Printf("energy ledger of the predators doesn't balance in generation %d: off by %d\n", stats.generation, imbalance)
+		balanced = false
+	}
+	return balanced
+}
+
+// LedgerHeader() returns the column names of the ledger file, in the same order as LedgerRow().
+func LedgerHeader() []string {
+	return []string{
+		"generation", "species", "startEnergy", "ingested", "moving", "basal", "mating", "disease",
+		"toOffspring", "lostAtDeath", "introduced", "emigrated", "immigrated", "endEnergy", "imbalance",
+	}
+}
+
+// LedgerRow() formats the ledger of one species in one generation as one row of the ledger file.
+func LedgerRow(generation int, species string, ledger EnergyLedger) []string {
+	values := []int{
+		ledger.startEnergy, ledger.ingested, ledger.moving, ledger.basal, ledger.mating, ledger.disease,
+		ledger.toOffspring, ledger.lostAtDeath, ledger.introduced, ledger.emigrated, ledger.immigrated, ledger.endEnergy, ledger.Imbalance(),
+	}
+	row := []string{strconv.Itoa(generation), species}
+	for _, value := range values {
+		row = append(row, strconv.Itoa(value))
+	}
+	return row
+}
+
+// WriteLedgerToFile() writes the energy ledgers of allStats to filename as a CSV file, one row per generation and species.
+// Generations whose books don't balance have a non-zero imbalance.
+func WriteLedgerToFile(allStats []GenerationStats, filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create ledger file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(LedgerHeader())
+	for _, stats := range allStats {
+		writer.Write(LedgerRow(stats.generation, "prey", stats.preyLedger))
+		writer.Write(LedgerRow(stats.generation, "predator", stats.predLedger))
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		panic("could not write ledger file " + filename + ": " + err.Error())
+	}
+}
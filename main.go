@@ -3,23 +3,201 @@ package main
 import (
 	"fmt"
 	"gifhelper"
+	"math"
 	"math/rand"
 	"time"
 )
 
 // GLOBAL VARIABLES
 
-// Set a constant dictionary where keys are the directionIndex and the values are the orderedPair with corresponding deltaX and deltaY
-var deltas map[int]OrderedPair
+// the PRNG every random choice of the simulation draws from, seeded approximately randomly. CompareBackends() swaps in a seeded one, so its runs can be repeated
+var rng *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Set a constant array where indices are the directionIndex and the values are the orderedPair with corresponding deltaX and deltaY.
+// it isn't a map, so looping over the directions always goes in the same order and a seeded run can be repeated
+var deltas [8]OrderedPair
+
+// energy taken from an organism for a move that turns by geneIndex, see DecreaseEnergy(). the values set in SetMovementTables() are all <= 0,
+// so as set a move never costs energy for its turn and sharp turns give energy back. that's the original model, which the predators depend on
+// to survive on energyPerPrey. a positive value makes a turn cost energy
 var energyCosts map[int]int
 var maxEnergy int = 1500
+var energyGainedPerPlankton int = 50
+
+// energy transfer between trophic levels, see EnergyTransferred(). "fixed", "fraction", or "cappedFraction"
+var energyTransferRule string = "fixed"
+var energyPerPrey int = 1           // what a predator gets from a prey under "fixed"
+var planktonEnergy int = 500        // energy held by one plankton, what "fraction" takes its share of
+var trophicEfficiency float64 = 0.1 // share of the food's energy that reaches the eater under "fraction"
+
+// food types, see FoodType. without any, all food is the same plankton worth energyGainedPerPlankton (planktonEnergy under "fraction").
+// every prey carries a heritable diet trait that says how well it digests each type, see DietEfficiency()
+var foodTypes []FoodType = []FoodType{}
+var minDietPreference float64 = 0.0 // prey don't eat food types they prefer less than this
+
+// founder values of the heritable traits. every organism carries its own copy in Organism.traits, which can evolve
 var energyThresholdPrey int = 50
 var ageThresholdPrey int = 21
 var costOfLivingPrey int = 0
-var energyGainedPerPlankton int = 50
+var speedPrey int = 1                 // cells per generation
+var offspringSharePrey float64 = 0.5  // share of the parent's energy given to the child
 var energyThresholdPredator int = 100 // 800
 var ageThresholdPredator int = 42     // 50
 var costOfLivingPredator int = 0
+var speedPredator int = 1
+var offspringSharePredator float64 = 0.5
+
+// speed. organisms move up to their speed trait in cells every generation, one sub-step at a time, see UpdatePrey() and UpdatePredator().
+// with speedEvolves the speed trait mutates like the other traits, otherwise every newborn gets the founder speed of its species.
+// every cell moved costs stepCost energy on top of the turn cost in energyCosts. with the default stepCost of 0 an extra cell is free
+// (or gives energy, see energyCosts), so speed only evolves when switched on, ideally with a stepCost that makes running cost something
+var speedEvolvesPrey bool = false
+var speedEvolvesPredator bool = false
+var stepCostPrey int = 0
+var stepCostPredator int = 0
+
+// senescence, see MortalityCurve. "none", "maxLifespan", "constant", or "gompertz"
+var mortalityPrey MortalityCurve = MortalityCurve{rule: "none", maxLifespan: 200, hazard: 0.001, gompertzRate: 0.05}
+var mortalityPredator MortalityCurve = MortalityCurve{rule: "none", maxLifespan: 400, hazard: 0.001, gompertzRate: 0.03}
+
+// functional response. after a kill a predator spends handlingTimePredator generations handling it, and it can't eat
+// while satiationCapPredator prey are in its gut. digestionRatePredator prey are digested every generation. 0 turns the handling time or cap off
+var handlingTimePredator int = 0
+var satiationCapPredator int = 0
+var digestionRatePredator int = 1
+
+// disease. infected organisms pass the infection to any organism among their 8 neighbours, see disease.go
+var diseaseEnabled bool = false
+var numInitiallyInfected int = 5
+var transmissionProbability float64 = 0.1 // chance per generation of infecting each susceptible neighbour
+var infectionEnergyDrain int = 2          // extra energy an infected organism loses every generation
+var diseaseMortality float64 = 0.01       // chance per generation that an infected organism dies of the disease
+var recoveryTime int = 10                 // generations until an infected organism recovers for good
+
+// fishing, see HarvestPolicy. rule is "none", "quota", "effort", or "selective"
+var harvestPolicy HarvestPolicy = HarvestPolicy{rule: "none", targetPrey: true, targetPredators: false, quota: 5, effort: 0.02, minAge: 10, minEnergy: 50}
+
+// no-take marine protected areas. protectedAreaFile is a mask file (see LoadMaskFromFile) added to protectedAreas when it isn't ""
+var protectedAreas []Region = []Region{}
+var protectedAreaFile string = ""
+
+// prey refuges, see Refuge. predators can't enter them, and each one shelters at most capacity prey (0 for no limit).
+// refugeFile is a mask file (see LoadMaskFromFile) added to refuges as one more refuge with refugeFileCapacity when it isn't ""
+var refuges []Refuge = []Refuge{}
+var refugeFile string = ""
+var refugeFileCapacity int = 0
+
+// metapopulation mode, see Patch and MigrationChannel. with any patches, main simulates them instead of the single Ecosystem
+// and writes the GIF, stats, ledger and genomes of every patch to its own file, plus a summary of all patches to patchSummaryFile
+var patches []Patch = []Patch{}
+var migrationChannels []MigrationChannel = []MigrationChannel{}
+var patchSummaryFile string = "patches.csv"
+
+// depth. with numLayers above 1 the ocean has numLayers layers of numRows x numCols Units, see Ocean, and organisms move between them
+// with their VerticalGenome. food grows in proportion to the light that reaches a layer, and predators hunt by sight, so prey can evolve
+// to dive by day and feed at the surface by night. interventions only apply to a flat ocean
+var numLayers int = 1
+var lightAttenuation float64 = 0.7          // light falls by a factor exp(-lightAttenuation) with every layer
+var nightLight float64 = 0.1                // light at night, relative to the day
+var dayLength int = 20                      // generations per day and night. the first half is day
+var verticalMoveCost int = 1                // energy it costs to move up or down a layer
+var verticalMutationStrength float64 = 0.05 // standard deviation of the noise on every weight of a child's VerticalGenome
+var depthView string = "slice"              // the GIF shows layer depthSlice with "slice", or the ocean seen from above with "projection"
+var depthSlice int = 0
+
+// temperature (in degrees Celsius) and light, see EnvironmentField. thermalRule says how the metabolic cost, the growth of food and the age
+// threshold for reproduction scale with the local temperature: "none", "q10", or "arrhenius", see ThermalFactor(). food grows in proportion to the light.
+// a field with the "file" rule is loaded from its filename, see LoadFieldFromFile(). a trend makes a climate-warming scenario
+var temperatureField EnvironmentField = EnvironmentField{rule: "static", value: 20, gradientEnd: 20, seasonLength: 100}
+var lightField EnvironmentField = EnvironmentField{rule: "static", value: 1, gradientEnd: 1, seasonLength: 100}
+var thermalRule string = "none"
+var referenceTemperature float64 = 20 // where ThermalFactor() is 1
+var q10 float64 = 2.0                 // how many times faster rates run 10 degrees warmer, for "q10"
+var activationEnergy float64 = 0.65   // in eV, for "arrhenius"
+
+// scripted interventions and catastrophes, see LoadInterventionsFromFile(). no interventions when interventionFile is ""
+var interventionFile string = ""
+var interventions []Intervention
+
+// nutrient cycling. with decompositionEnabled dead organisms leave their energy behind as detritus, see decomposition.go
+var decompositionEnabled bool = false
+var decayRate float64 = 0.2      // share of the detritus that decays into nutrients every generation
+var nutrientSpread float64 = 0.1 // share of the nutrients that spreads to the 8 neighbours every generation
+
+// how the founding genomes are made. "uniform", "dirichlet", "cruiser", "circler", or "file"
+var genomeRulePrey string = "uniform"
+var genomeRulePredator string = "uniform"
+var dirichletConcentration float64 = 1.0    // small values give lopsided genomes, large values give genomes close to uniform
+var genomeFile string = "genomes.txt"       // founder genomes, read (never written) when a genomeRule is "file"
+var genomeExportFile string = "evolved.txt" // the final genomes, written at the end of every run
+
+// perception. founder vision radius; prey with a vision radius of 0 don't sense anything and move on their genome alone
+var visionRadiusPrey int = 0
+var foodAttractionPrey float64 = 1.0    // founder value of the heritable pull towards food
+var predatorAversionPrey float64 = 1.0  // founder value of the heritable push away from predators
+var traitMutationStrength float64 = 0.1 // standard deviation of trait mutation, relative to the trait for integer traits
+
+// movement, see MovementPolicy. GenomeWalk, CorrelatedWalk, LevyFlight or Greedy
+var movementPrey MovementPolicy = GenomeWalk{}
+var movementPredator MovementPolicy = GenomeWalk{}
+
+// behaviour. "randomWalk" moves with the MovementPolicy of the species, "hunting" steps towards the nearest prey (for predators) or food (for prey) in sight
+var behaviourPrey string = "randomWalk"
+var behaviourPredator string = "randomWalk"
+var visionRadiusPredator int = 3
+var captureProbability float64 = 0.5 // chance that a predator's pounce on a prey succeeds
+var chaseEnergyCostPrey int = 0      // energy per cell of distance to the food being chased
+var chaseEnergyCostPredator int = 1  // energy per cell of distance to the prey being chased
+
+// schooling. with flockingPrey the prey within flockRadius bias the genome's direction choice
+var flockingPrey bool = false
+var flockRadius int = 2
+var alignmentWeight float64 = 1.0  // steer the same way as the neighbours
+var cohesionWeight float64 = 1.0   // steer towards the middle of the neighbours
+var separationWeight float64 = 0.5 // steer away from the neighbours that are touching
+
+// reproduction. "asexual" copies one parent, "sexual" needs an eligible neighbour and mixes both genomes
+var reproductionModePrey string = "asexual"
+var reproductionModePredator string = "asexual"
+var crossoverRule string = "uniform" // "uniform", "onePoint", or "blend"
+var matingCostPrey int = 5           // energy each parent pays to mate
+var matingCostPredator int = 5
+
+// mutation of a child's direction genome. LegacyMutation, GaussianMutation, DirichletMutation and TransferMutation each take their own rate and strength, see mutation.go
+var mutationPrey Mutation = LegacyMutation{rate: 1.0, delta: 0.8}
+var mutationPredator Mutation = LegacyMutation{rate: 1.0, delta: 0.8}
+
+var statsFile string = "stats.csv"
+
+// the energy ledger of every generation and species is written here, see EnergyLedger
+var ledgerFile string = "ledger.csv"
+
+// storage backend. "dense" keeps a Unit for every cell, "sparse" only keeps the organisms and a bitset of the food (see SparseEcosystem),
+// which makes very large, mostly empty oceans practical. both run the same rules through a Board, but the sparse backend (and the gillespie engine, which runs on it)
+// doesn't support foodTypes, refuges, disease, decomposition, harvesting, numLayers above 1, patches or interventions, and panics if they are set, see CheckSimpleModel().
+// a sparse or continuous run keeps every sparseKeepEvery-th generation for the GIF. above maxDrawnUnits Units it isn't drawn at all,
+// and only keeps the first and the last generation, see KeepEvery().
+// with compareBackendsReplicates above 0, main compares the two backends instead, see CompareBackends()
+var storageBackend string = "dense"
+var sparseKeepEvery int = 1
+var maxDrawnUnits int = 1000000
+var compareBackendsReplicates int = 0
+
+// simulation engine. "lattice" is the grid of Units (with the storageBackend above), "continuous" gives the organisms float positions and headings, see ContinuousOcean,
+// and "gillespie" runs the lattice in continuous time, see GillespieSimulation. a gillespie run lasts totalTimesteps time units, and has one row of stats
+// per gillespieInterval (the generation column counts the intervals).
+// in continuous space every step turns an organism by its gene index times 45 degrees plus Gaussian noise of turnNoise radians, and moves it stepLength Units.
+// predators catch a prey within captureRadius, prey eat the food of a Unit whose centre is within feedingRadius, and mates, newborns and schooling neighbours are within interactionRadius.
+// no move or newborn may come within exclusionRadius of another organism, except a predator that can eat closing in on a prey, see IsCrowded().
+// at 0.8 a crowded ocean holds about one organism per Unit, like the lattice. 0 lets them overlap, so reproduction is never blocked
+var simulationEngine string = "lattice"
+var gillespieInterval float64 = 1.0
+var turnNoise float64 = 0.3
+var stepLength float64 = 1.0
+var captureRadius float64 = 0.75
+var feedingRadius float64 = 0.75
+var interactionRadius float64 = 1.5
+var exclusionRadius float64 = 0.8
 
 // DON'T MESS WITH THIS. SET THEM IN MAIN
 // we will use these to track numPrey and numPred globally
@@ -28,28 +206,9 @@ var numPred int = 0
 
 func main() {
 
-	// assign deltas
-	deltas = map[int]OrderedPair{
-		0: {-1, 1},
-		1: {0, 1},
-		2: {1, 1},
-		3: {-1, 0},
-		4: {1, 0},
-		5: {-1, -1},
-		6: {0, -1},
-		7: {1, -1},
-	}
-
-	energyCosts = map[int]int{
-		0: 0,
-		1: -1,
-		2: -2,
-		3: -4,
-		4: -8,
-		5: -4,
-		6: -2,
-		7: -1,
-	}
+	// reject bad settings above before anything runs, e.g. a dayLength below 1
+	CheckParameters()
+	SetMovementTables()
 
 	// var numRows int = 250
 	// var numCols int = 250
@@ -58,30 +217,177 @@ func main() {
 	numPrey = 10
 	numPred = 50
 
+	if protectedAreaFile != "" {
+		protectedAreas = append(protectedAreas, LoadMaskFromFile(protectedAreaFile))
+	}
+
+	if refugeFile != "" {
+		refuges = append(refuges, Refuge{area: LoadMaskFromFile(refugeFile), capacity: refugeFileCapacity})
+	}
+
+	if temperatureField.rule == "file" {
+		temperatureField.values = LoadFieldFromFile(temperatureField.filename)
+	}
+	if lightField.rule == "file" {
+		lightField.values = LoadFieldFromFile(lightField.filename)
+	}
+
+	if interventionFile != "" {
+		interventions = LoadInterventionsFromFile(interventionFile)
+	}
+
+	// load the founders for transplant experiments, e.g. the population exported from an earlier run, and for the interventions that add them
+	fileSpecies := GenomeFileSpecies(interventions)
+	if genomeRulePrey == "file" {
+		fileSpecies = append(fileSpecies, "prey")
+	}
+	if genomeRulePredator == "file" {
+		fileSpecies = append(fileSpecies, "predator")
+	}
+	if len(fileSpecies) != 0 {
+		founderGenomes = LoadGenomesFromFile(genomeFile)
+		for _, species := range fileSpecies {
+			if err := CheckGenomeRule("file", species); err != nil {
+				panic(err.Error())
+			}
+		}
+	}
+
 	if (numRows * numCols) < (numPrey + numPred) {
 		panic("there's too many predator and prey in total")
 	}
-	var initialEcosystem Ecosystem = InitializeEcosystem(numRows, numCols, numPrey, numPred)
 	var totalTimesteps int = 10
 	var foodRule string = "gardenOfEden"
 
-	// seed the PRNG approximately randomly
-	rand.Seed(time.Now().UnixNano())
-	allEcosystems := SimulateEcosystemEvolution(&initialEcosystem, totalTimesteps, foodRule)
-
 	canvasWidth := 1000
 	frequency := 1
 	scalingFactor := 1.0
+
+	if len(patches) != 0 {
+		SimulateMetapopulation(patches, migrationChannels, totalTimesteps)
+		for _, somePatch := range patches {
+			gifhelper.ImagesToGIF(AnimateSystem(somePatch.ecosystems, canvasWidth, frequency, scalingFactor), "ecosystem_"+somePatch.name)
+			WriteStatsToFile(somePatch.stats, PatchFilename(statsFile, somePatch.name))
+			WriteLedgerToFile(somePatch.stats, PatchFilename(ledgerFile, somePatch.name))
+			WriteGenomesToFile(somePatch.ecosystems[len(somePatch.ecosystems)-1], PatchFilename(genomeExportFile, somePatch.name))
+		}
+		WritePatchSummaryToFile(patches, patchSummaryFile)
+		fmt.Println("Output of", len(patches), "patches written. Summary written to", patchSummaryFile)
+		return
+	}
+
+	if compareBackendsReplicates > 0 {
+		PrintBackendComparison(CompareBackends(numRows, numCols, numPrey, numPred, totalTimesteps, foodRule, compareBackendsReplicates, rng))
+		return
+	}
+
+	// the other engines and the sparse backend only keep some generations, see Snapshot
+	var snapshots []Snapshot
+	keepEvery := KeepEvery(numRows, numCols)
+	if simulationEngine == "continuous" {
+		initialOcean := InitializeContinuousOcean(numRows, numCols, numPrey, numPred)
+		for _, someOcean := range SimulateContinuousOcean(initialOcean, totalTimesteps, foodRule, keepEvery) {
+			snapshots = append(snapshots, someOcean)
+		}
+	} else if simulationEngine == "gillespie" {
+		initialSparse := InitializeSparseEcosystem(numRows, numCols, numPrey, numPred)
+		for _, someEcosystem := range SimulateGillespie(initialSparse, float64(totalTimesteps), gillespieInterval, foodRule, keepEvery) {
+			snapshots = append(snapshots, someEcosystem)
+		}
+	} else if simulationEngine != "lattice" {
+		panic("invalid simulationEngine string inputted. should be lattice, continuous, or gillespie!")
+	} else if storageBackend == "sparse" {
+		initialSparse := InitializeSparseEcosystem(numRows, numCols, numPrey, numPred)
+		for _, someEcosystem := range SimulateSparseEcosystemEvolution(initialSparse, totalTimesteps, foodRule, keepEvery) {
+			snapshots = append(snapshots, someEcosystem)
+		}
+	} else if storageBackend != "dense" {
+		panic("invalid storageBackend string inputted. should be dense or sparse!")
+	}
+
+	if len(snapshots) != 0 {
+		if numRows*numCols <= maxDrawnUnits {
+			gifhelper.ImagesToGIF(AnimateSystem(RasteriseSnapshots(snapshots), canvasWidth, frequency, scalingFactor), "ecosystem")
+			fmt.Println("GIF drawn.")
+		}
+		WriteStatsToFile(allStats, statsFile)
+		WriteLedgerToFile(allStats, ledgerFile)
+		snapshots[len(snapshots)-1].WriteGenomesToFile(genomeExportFile)
+		fmt.Println("Stats, energy ledger and genomes written to", statsFile, ledgerFile, genomeExportFile)
+		return
+	}
+
+	// the Ecosystems to draw, and the final population, whose genomes are exported
+	var allEcosystems []*Ecosystem
+	var finalEcosystem *Ecosystem
+	if numLayers > 1 {
+		initialOcean := InitializeOcean(numRows, numCols, numPrey, numPred)
+		allOceans := SimulateOcean(&initialOcean, totalTimesteps, foodRule)
+		for _, someOcean := range allOceans {
+			allEcosystems = append(allEcosystems, OceanView(someOcean))
+		}
+		finalEcosystem = StackLayers(allOceans[len(allOceans)-1])
+	} else {
+		var initialEcosystem Ecosystem = InitializeEcosystem(numRows, numCols, numPrey, numPred)
+		allEcosystems = SimulateEcosystemEvolution(&initialEcosystem, totalTimesteps, foodRule)
+		finalEcosystem = allEcosystems[len(allEcosystems)-1]
+	}
+
 	imageList := AnimateSystem(allEcosystems, canvasWidth, frequency, scalingFactor)
 
 	gifhelper.ImagesToGIF(imageList, "ecosystem")
 	fmt.Println("GIF drawn.")
 
+	WriteStatsToFile(allStats, statsFile)
+	fmt.Println("Stats written to", statsFile)
+
+	WriteLedgerToFile(allStats, ledgerFile)
+	fmt.Println("Energy ledger written to", ledgerFile)
+
+	// export the evolved population so it can seed a later run
+	WriteGenomesToFile(finalEcosystem, genomeExportFile)
+	fmt.Println("Genomes written to", genomeExportFile)
+
 	// use this for debugging and seeing characteristics of specific ecosystem(s)
 	// PrintEcosystem(allEcosystems[len(allEcosystems)-1])
 
 }
 
+// SetMovementTables() fills in deltas and energyCosts, which every move looks up. main calls it first, and so do the tests.
+func SetMovementTables() {
+	// assign deltas
+	deltas = [8]OrderedPair{
+		0: {-1, 1},
+		1: {0, 1},
+		2: {1, 1},
+		3: {-1, 0},
+		4: {1, 0},
+		5: {-1, -1},
+		6: {0, -1},
+		7: {1, -1},
+	}
+
+	energyCosts = map[int]int{
+		0: 0,
+		1: -1,
+		2: -2,
+		3: -4,
+		4: -8,
+		5: -4,
+		6: -2,
+		7: -1,
+	}
+}
+
+// KeepEvery() is how often a run of numRows x numCols Units that only keeps some generations keeps one: every sparseKeepEvery-th generation,
+// or, if it is too big to draw, so rarely that only the first and the last generation (whose genomes are exported) are kept, and the others don't pile up in memory.
+func KeepEvery(numRows, numCols int) int {
+	if numRows*numCols > maxDrawnUnits {
+		return math.MaxInt32
+	}
+	return sparseKeepEvery
+}
+
 func PrintEcosystem(someEcosystem *Ecosystem) {
 	countPrey, countPred := 0, 0
 	checkIfTwo := false
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Patch is one island of a metapopulation: an Ecosystem of its own size, with its own food rule and starting population.
+// parameters overrides global parameters (by the names SetParameter() knows) while the patch is set up and updated,
+// so e.g. one patch can be a rich source and another a sink. Interventions only apply to single-grid runs.
+type Patch struct {
+	name             string
+	numRows, numCols int
+	numPrey, numPred int
+	foodRule         string
+	parameters       map[string]string
+
+	ecosystems []*Ecosystem      // one per generation, filled in by SimulateMetapopulation()
+	stats      []GenerationStats // one per generation
+}
+
+// MigrationChannel moves organisms from patch from to patch to (indices into patches). rule picks who leaves:
+// with "edge" every organism on the outermost ring of Units of the patch leaves with probability rate, as if it swam off the edge, and arrives on the edge of the other patch;
+// with "random" every organism leaves with probability rate and arrives at a random Unit.
+// species is "prey", "predator" or "both". Migrants are in transit for delay generations (at least 1), and are lost if there is no free Unit for them when they arrive.
+type MigrationChannel struct {
+	from, to int
+	rule     string
+	species  string
+	rate     float64
+	delay    int
+}
+
+// Migrant is an organism in transit along a MigrationChannel. exactly one of prey and predator is set.
+type Migrant struct {
+	prey     *Prey
+	predator *Predator
+	to       int  // the patch it is heading for
+	onEdge   bool // arrives on the edge of the patch
+	arrival  int  // generation in which it arrives
+}
+
+// SimulateMetapopulation() simulates every patch for totalTimesteps generations, moving organisms along channels between them.
+// Each generation the patches are updated in turn with their own parameters. After its update, migrants due in a patch arrive, then its emigrants leave.
+// The Ecosystems and stats of every generation are stored in the patches.
+func SimulateMetapopulation(patches []Patch, channels []MigrationChannel, totalTimesteps int) {
+	fmt.Println("SimulateMetapopulation is running")
+	CheckMetapopulation(patches, channels)
+
+	for p := range patches {
+		previous := SetParameters(patches[p].parameters)
+		initialEcosystem := InitializeEcosystem(patches[p].numRows, patches[p].numCols, patches[p].numPrey, patches[p].numPred)
+		ResetStats(0)
+		curStats.preyLedger.introduced, curStats.predLedger.introduced = SpeciesEnergy(&initialEcosystem)
+		patches[p].ecosystems = []*Ecosystem{&initialEcosystem}
+		patches[p].stats = []GenerationStats{FinishStats(&initialEcosystem)}
+		SetParameters(previous)
+	}
+
+	var inTransit []Migrant
+	for curGen := 1; curGen <= totalTimesteps; curGen++ {
+		for p := range patches {
+			previous := SetParameters(patches[p].parameters)
+
+			nextEcosystem := UpdateEcosystem(patches[p].ecosystems[curGen-1], patches[p].foodRule, curGen)
+			inTransit = ArriveMigrants(nextEcosystem, p, curGen, inTransit)
+			inTransit = append(inTransit, EmigrateOrganisms(nextEcosystem, p, curGen, channels)...)
+
+			patches[p].ecosystems = append(patches[p].ecosystems, nextEcosystem)
+			patches[p].stats = append(patches[p].stats, FinishStats(nextEcosystem))
+
+			SetParameters(previous)
+		}
+
+		// print status of simulation
+		if (totalTimesteps / 10) != 0 {
+			if curGen%(totalTimesteps/10) == 0 || curGen == 1 {
+				fmt.Println("Simulation is", 100*float64(curGen)/float64(totalTimesteps), "percent complete. Generation =", curGen)
+			}
+		}
+	}
+}
+
+// CheckMetapopulation() panics if a patch can't hold its starting population or a channel is invalid, so a typo doesn't silently change the experiment.
+func CheckMetapopulation(patches []Patch, channels []MigrationChannel) {
+	for _, somePatch := range patches {
+		if somePatch.numRows*somePatch.numCols < somePatch.numPrey+somePatch.numPred {
+			panic("there's too many predator and prey in total in patch " + somePatch.name)
+		}
+	}
+
+	for _, channel := range channels {
+		if channel.from < 0 || channel.from >= len(patches) || channel.to < 0 || channel.to >= len(patches) {
+			panic(fmt.Sprintf("migration channel %d -> %d connects a patch that doesn't exist", channel.from, channel.to))
+		}
+		if channel.rule != "edge" && channel.rule != "random" {
+			panic("invalid migration rule string inputted. should be edge or random!")
+		}
+		if channel.species != "prey" && channel.species != "predator" && channel.species != "both" {
+			panic("invalid migration species string inputted. should be prey, predator, or both!")
+		}
+		if channel.delay < 1 {
+			panic("the delay of a migration channel must be at least 1 generation")
+		}
+	}
+}
+
+// EmigrateOrganisms() removes the organisms that leave patch p in generation curGen along each of channels, and records them in curStats.
+// Output: the migrants, now in transit
+func EmigrateOrganisms(someEcosystem *Ecosystem, p, curGen int, channels []MigrationChannel) []Migrant {
+	var leaving []Migrant
+	for _, channel := range channels {
+		if channel.from != p {
+			continue
+		}
+		onEdge := channel.rule == "edge"
+
+		for i := range *someEcosystem {
+			for j := range (*someEcosystem)[i] {
+				if onEdge && !IsEdgeUnit(someEcosystem, i, j) {
+					continue
+				}
+				curUnit := (*someEcosystem)[i][j]
+
+				if curUnit.prey != nil && channel.species != "predator" && rng.Float64() < channel.rate {
+					leaving = append(leaving, Migrant{prey: curUnit.prey, to: channel.to, onEdge: onEdge, arrival: curGen + channel.delay})
+					curStats.preyEmigrated++
+					curStats.preyLedger.emigrated += curUnit.prey.energy
+					curUnit.prey = nil
+				}
+				if curUnit.predator != nil && channel.species != "prey" && rng.Float64() < channel.rate {
+					leaving = append(leaving, Migrant{predator: curUnit.predator, to: channel.to, onEdge: onEdge, arrival: curGen + channel.delay})
+					curStats.predEmigrated++
+					curStats.predLedger.emigrated += curUnit.predator.energy
+					curUnit.predator = nil
+				}
+			}
+		}
+	}
+	return leaving
+}
+
+// ArriveMigrants() places the migrants of inTransit that are due in patch p by generation curGen at random free Units of someEcosystem
+// (on its edge, for the "edge" rule), and records them in curStats. migrants that find no free Unit are lost.
+// Output: the migrants that are still in transit
+func ArriveMigrants(someEcosystem *Ecosystem, p, curGen int, inTransit []Migrant) []Migrant {
+	var stillInTransit []Migrant
+	for _, migrant := range inTransit {
+		if migrant.to != p || migrant.arrival > curGen {
+			stillInTransit = append(stillInTransit, migrant)
+			continue
+		}
+
+		// a prey needs a Unit without a prey or predator and with room in its refuge, a predator one without either that isn't in a refuge
+		var freeUnits []*Unit
+		for i := range *someEcosystem {
+			for j := range (*someEcosystem)[i] {
+				curUnit := (*someEcosystem)[i][j]
+				if curUnit.prey != nil || curUnit.predator != nil || (migrant.onEdge && !IsEdgeUnit(someEcosystem, i, j)) {
+					continue
+				}
+				if migrant.prey != nil && curUnit.refuge.HasRoom(someEcosystem, nil) || migrant.predator != nil && curUnit.refuge == nil {
+					freeUnits = append(freeUnits, curUnit)
+				}
+			}
+		}
+		if len(freeUnits) == 0 {
+			curStats.migrantsLost++
+			continue
+		}
+
+		chosenUnit := freeUnits[rng.Intn(len(freeUnits))]
+		if migrant.prey != nil {
+			chosenUnit.prey = migrant.prey
+			curStats.preyImmigrated++
+			curStats.preyLedger.immigrated += migrant.prey.energy
+		} else {
+			chosenUnit.predator = migrant.predator
+			curStats.predImmigrated++
+			curStats.predLedger.immigrated += migrant.predator.energy
+		}
+	}
+	return stillInTransit
+}
+
+// IsEdgeUnit() checks whether Unit i, j is on the outermost ring of Units of someEcosystem.
+func IsEdgeUnit(someEcosystem *Ecosystem, i, j int) bool {
+	return i == 0 || j == 0 || i == someEcosystem.CountRows()-1 || j == someEcosystem.CountCols()-1
+}
+
+// PatchFilename() inserts the name of a patch before the extension of filename, e.g. stats.csv becomes stats_north.csv.
+func PatchFilename(filename, patchName string) string {
+	extension := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, extension) + "_" + patchName + extension
+}
+
+// WritePatchSummaryToFile() writes the population and migration of every patch in every generation to filename as a CSV file,
+// one row per generation and patch, for source-sink and rescue analyses. the full stats of each patch are in its own stats file.
+func WritePatchSummaryToFile(patches []Patch, filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create patch summary file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"generation", "patch", "numPrey", "numPred", "preyEmigrated", "predEmigrated", "preyImmigrated", "predImmigrated", "migrantsLost"})
+	for _, somePatch := range patches {
+		for _, stats := range somePatch.stats {
+			writer.Write([]string{
+				strconv.Itoa(stats.generation), somePatch.name, strconv.Itoa(stats.numPrey), strconv.Itoa(stats.numPred),
+				strconv.Itoa(stats.preyEmigrated), strconv.Itoa(stats.predEmigrated), strconv.Itoa(stats.preyImmigrated), strconv.Itoa(stats.predImmigrated),
+				strconv.Itoa(stats.migrantsLost),
+			})
+		}
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		panic("could not write patch summary file " + filename + ": " + err.Error())
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math"
+)
+
+// MovementPolicy decides where an organism moves next. Each species has its own, set in movementPrey and movementPredator,
+// and the "hunting" behaviour falls back to it when nothing is in sight.
+// Move returns the same values for every policy: deltaRow, deltaCol (the whole displacement), newDirection, geneIndex (the turn, which sets the energy cost, see energyCosts), newI, newJ.
+// An organism that can't move gets 0, 0 and its own indices.
+type MovementPolicy interface {
+	Move(board Board, mover Mover, i, j int) (int, int, int, int, int, int)
+}
+
+// Mover is what a MovementPolicy needs to know about the organism it moves. *Prey and *Predator are Movers.
+type Mover interface {
+	Body() *Organism
+	CanMoveTo(board Board, i, j int) bool              // whether the organism may enter Unit i, j
+	DirectionWeights(board Board, i, j int) [8]float64 // the weight of every gene index, see ChooseGeneIndex()
+	CellValue(board Board, i, j int) float64           // how good Unit i, j is to move to, for Greedy
+}
+
+// GenomeWalk is the original movement model: the organism turns by a gene index chosen with the weights of its genome (and, for prey, its perception),
+// relative to its lastDirection, and moves one cell.
+type GenomeWalk struct{}
+
+// CorrelatedWalk keeps the organism's heading with probability persistence and otherwise turns to a direction chosen uniformly at random.
+// It moves one cell and ignores the genome.
+type CorrelatedWalk struct {
+	persistence float64
+}
+
+// LevyFlight jumps in a direction chosen uniformly at random. The jump length follows a power law with the given exponent
+// (between 1 and 3, smaller exponents give more long jumps), capped at maxJump cells. A jump stops short before the first cell the organism can't enter,
+// and on the first cell it passes that is worth stopping for (see CellValue()), so a shark doesn't jump over a prey. It ignores the genome.
+type LevyFlight struct {
+	exponent float64
+	maxJump  int
+}
+
+// Greedy moves to the neighbouring cell with the highest CellValue, breaking ties at random. When no neighbour is worth anything it falls back to GenomeWalk.
+type Greedy struct{}
+
+// Body() returns the Organism shared by prey and predators.
+func (someOrganism *Organism) Body() *Organism {
+	return someOrganism
+}
+
+// CanMoveTo() of a prey also keeps it out of a full refuge, see HasRoom()
+func (currentPrey *Prey) CanMoveTo(board Board, i, j int) bool {
+	return isFreeUnit(board, i, j) && board.RefugeAt(i, j).HasRoom(board, currentPrey)
+}
+
+func (currentPrey *Prey) DirectionWeights(board Board, i, j int) [8]float64 {
+	return PreyDirectionWeights(board, currentPrey, i, j)
+}
+
+// CellValue() of a prey is what it would get from the food in Unit i, j, relative to the plain plankton, see DietEfficiency().
+func (currentPrey *Prey) CellValue(board Board, i, j int) float64 {
+	someFood := board.FoodAt(i, j)
+	if !currentPrey.WillEat(someFood) {
+		return 0
+	}
+	fixedGain, _ := FoodEnergy(someFood.foodType)
+	return DietEfficiency(currentPrey.traits.diet, someFood.foodType) * float64(fixedGain)
+}
+
+func (shark *Predator) CanMoveTo(board Board, i, j int) bool {
+	return shark.isFreeUnit(board, i, j)
+}
+
+func (shark *Predator) DirectionWeights(board Board, i, j int) [8]float64 {
+	return GenomeWeights(shark.genome)
+}
+
+// CellValue() of a shark is 1 for a prey it can eat and 0 otherwise.
+func (shark *Predator) CellValue(board Board, i, j int) float64 {
+	if board.PreyAt(i, j) != nil && shark.CanEat() {
+		return 1
+	}
+	return 0
+}
+
+func (policy GenomeWalk) Move(board Board, mover Mover, i, j int) (int, int, int, int, int, int) {
+	// the weights only depend on the surroundings, so work them out once before trying to move
+	weights := mover.DirectionWeights(board, i, j)
+	return TryTurns(board, mover, i, j, func() int {
+		return ChooseGeneIndex(weights)
+	})
+}
+
+func (policy CorrelatedWalk) Move(board Board, mover Mover, i, j int) (int, int, int, int, int, int) {
+	return TryTurns(board, mover, i, j, func() int {
+		if rng.Float64() < policy.persistence {
+			return 0
+		}
+		return rng.Intn(8)
+	})
+}
+
+func (policy LevyFlight) Move(board Board, mover Mover, i, j int) (int, int, int, int, int, int) {
+	if policy.exponent <= 1 {
+		panic("the exponent of a LevyFlight must be greater than 1")
+	}
+	lastDirection := mover.Body().lastDirection
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+
+	// inverse transform sampling of a Pareto distribution with minimum 1
+	jump := int(math.Pow(1-rng.Float64(), -1/(policy.exponent-1)))
+	if jump > policy.maxJump {
+		jump = policy.maxJump
+	}
+
+	// same as TryTurns(): up to 20 directions, until the first cell of the jump is free
+	for numTries := 0; numTries < 20; numTries++ {
+		newDirection := rng.Intn(8)
+		moveDeltas := deltas[newDirection]
+
+		length := 0
+		for length < jump {
+			nextI := GetIndex(i, (length+1)*moveDeltas.row, numRows)
+			nextJ := GetIndex(j, (length+1)*moveDeltas.col, numCols)
+			if !mover.CanMoveTo(board, nextI, nextJ) {
+				break
+			}
+			length++
+			if mover.CellValue(board, nextI, nextJ) > 0 {
+				break
+			}
+		}
+		if length != 0 {
+			geneIndex := (newDirection - lastDirection + 8) % 8
+			return length * moveDeltas.row, length * moveDeltas.col, newDirection, geneIndex, GetIndex(i, length*moveDeltas.row, numRows), GetIndex(j, length*moveDeltas.col, numCols)
+		}
+	}
+
+	return 0, 0, lastDirection, 0, i, j
+}
+
+func (policy Greedy) Move(board Board, mover Mover, i, j int) (int, int, int, int, int, int) {
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+
+	bestValue := 0.0
+	var bestDirections []int
+	for direction, moveDeltas := range deltas {
+		newI := GetIndex(i, moveDeltas.row, numRows)
+		newJ := GetIndex(j, moveDeltas.col, numCols)
+		if !mover.CanMoveTo(board, newI, newJ) {
+			continue
+		}
+		value := mover.CellValue(board, newI, newJ)
+		if value > bestValue {
+			bestValue = value
+			bestDirections = []int{direction}
+		} else if value == bestValue && value > 0 {
+			bestDirections = append(bestDirections, direction)
+		}
+	}
+
+	if len(bestDirections) == 0 {
+		return GenomeWalk{}.Move(board, mover, i, j)
+	}
+
+	newDirection := bestDirections[rng.Intn(len(bestDirections))]
+	moveDeltas := deltas[newDirection]
+	geneIndex := (newDirection - mover.Body().lastDirection + 8) % 8
+	return moveDeltas.row, moveDeltas.col, newDirection, geneIndex, GetIndex(i, moveDeltas.row, numRows), GetIndex(j, moveDeltas.col, numCols)
+}
+
+// TryTurns() moves the organism one cell, turning by the gene index chooseTurn() returns relative to its lastDirection.
+// 20 is the threshold for max number of tries we get to reselect a turn: if none of them leads to a Unit the organism can enter, it doesn't move.
+func TryTurns(board Board, mover Mover, i, j int, chooseTurn func() int) (int, int, int, int, int, int) {
+	lastDirection := mover.Body().lastDirection
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+
+	for numTries := 0; numTries < 20; numTries++ {
+		geneIndex := chooseTurn()
+		newDirection := (lastDirection + geneIndex) % 8
+		moveDeltas := deltas[newDirection]
+		newI := GetIndex(i, moveDeltas.row, numRows)
+		newJ := GetIndex(j, moveDeltas.col, numCols)
+
+		if mover.CanMoveTo(board, newI, newJ) {
+			//lastDirection will be updated with my new direction
+			return moveDeltas.row, moveDeltas.col, newDirection, geneIndex, newI, newJ
+		}
+	}
+
+	// if we still haven't found a free unit, we don't move
+	return 0, 0, lastDirection, 0, i, j
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Mutation is an operator that changes a child's direction genome after it has been copied from its parent(s).
+// Every Mutation must leave the genome a valid probability distribution (see CheckGenome).
+// Each species has its own Mutation, set in mutationPrey and mutationPredator.
+type Mutation interface {
+	Mutate(child *Organism)
+}
+
+// LegacyMutation is the original operator: with probability rate, the gene of the child's lastDirection is boosted by delta times itself
+// and the other genes shrink to make room (see UpdateGenome). The genome is renormalised afterwards.
+type LegacyMutation struct {
+	rate  float64
+	delta float64
+}
+
+// GaussianMutation adds Gaussian noise with standard deviation strength to every gene with probability rate, then renormalises.
+type GaussianMutation struct {
+	rate     float64
+	strength float64
+}
+
+// DirichletMutation replaces the genome, with probability rate, by a Dirichlet sample centred on it.
+// Larger concentration keeps the new genome closer to the old one.
+type DirichletMutation struct {
+	rate          float64
+	concentration float64
+}
+
+// TransferMutation moves, with probability rate, a random share of up to amount from one random gene to another.
+// It never takes more than the donor gene has, so the genome stays a valid distribution without renormalising.
+type TransferMutation struct {
+	rate   float64
+	amount float64
+}
+
+// MutateGenome() applies someMutation to child and panics if the genome it leaves behind isn't a valid probability distribution.
+func MutateGenome(child *Organism, someMutation Mutation) {
+	someMutation.Mutate(child)
+	if !CheckGenome(child.genome) {
+		panic(fmt.Sprintf("mutation %T left an invalid genome %v", someMutation, child.genome))
+	}
+}
+
+func (mutation LegacyMutation) Mutate(child *Organism) {
+	if rng.Float64() >= mutation.rate {
+		return
+	}
+	UpdateGenome(child, mutation.delta)
+	child.genome = NormalizeGenome(child.genome)
+}
+
+func (mutation GaussianMutation) Mutate(child *Organism) {
+	for i := range child.genome {
+		if rng.Float64() < mutation.rate {
+			child.genome[i] += Gene(rng.NormFloat64() * mutation.strength)
+		}
+	}
+	// NormalizeGenome() also clips the genes that went negative
+	child.genome = NormalizeGenome(child.genome)
+}
+
+func (mutation DirichletMutation) Mutate(child *Organism) {
+	if rng.Float64() >= mutation.rate {
+		return
+	}
+
+	// every gene gets shape concentration * gene, so the mean of the sample is the old genome.
+	// the small constant keeps genes that dropped to 0 from being stuck there forever
+	var newGenome [8]Gene
+	for i := range child.genome {
+		newGenome[i] = Gene(GammaSample(mutation.concentration*float64(child.genome[i]) + 0.01))
+	}
+	child.genome = NormalizeGenome(newGenome)
+}
+
+func (mutation TransferMutation) Mutate(child *Organism) {
+	if rng.Float64() >= mutation.rate {
+		return
+	}
+
+	donor := rng.Intn(len(child.genome))
+	receiver := rng.Intn(len(child.genome) - 1)
+	// skip over the donor so the two genes are always different
+	if receiver >= donor {
+		receiver++
+	}
+
+	share := Gene(rng.Float64() * mutation.amount)
+	if share > child.genome[donor] {
+		share = child.genome[donor]
+	}
+	child.genome[donor] -= share
+	child.genome[receiver] += share
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the global parameters that can be changed by name while a simulation runs, e.g. by an Intervention.
+// parameters that only set founder values (like energyThresholdPrey) only affect organisms created after the change,
+// and numLayers only shapes the Ocean when it is made, so changing it later only switches the mutation of the VerticalGenome on or off.
+var intParameters = map[string]*int{
+	"maxEnergy":               &maxEnergy,
+	"energyGainedPerPlankton": &energyGainedPerPlankton,
+	"energyPerPrey":           &energyPerPrey,
+	"planktonEnergy":          &planktonEnergy,
+	"energyThresholdPrey":     &energyThresholdPrey,
+	"ageThresholdPrey":        &ageThresholdPrey,
+	"costOfLivingPrey":        &costOfLivingPrey,
+	"speedPrey":               &speedPrey,
+	"energyThresholdPredator": &energyThresholdPredator,
+	"ageThresholdPredator":    &ageThresholdPredator,
+	"costOfLivingPredator":    &costOfLivingPredator,
+	"speedPredator":           &speedPredator,
+	"handlingTimePredator":    &handlingTimePredator,
+	"satiationCapPredator":    &satiationCapPredator,
+	"digestionRatePredator":   &digestionRatePredator,
+	"infectionEnergyDrain":    &infectionEnergyDrain,
+	"recoveryTime":            &recoveryTime,
+	"visionRadiusPrey":        &visionRadiusPrey,
+	"visionRadiusPredator":    &visionRadiusPredator,
+	"chaseEnergyCostPrey":     &chaseEnergyCostPrey,
+	"chaseEnergyCostPredator": &chaseEnergyCostPredator,
+	"matingCostPrey":          &matingCostPrey,
+	"matingCostPredator":      &matingCostPredator,
+	"stepCostPrey":            &stepCostPrey,
+	"stepCostPredator":        &stepCostPredator,
+	"verticalMoveCost":        &verticalMoveCost,
+	"numLayers":               &numLayers,
+	"dayLength":               &dayLength,
+}
+
+var floatParameters = map[string]*float64{
+	"trophicEfficiency":       &trophicEfficiency,
+	"offspringSharePrey":      &offspringSharePrey,
+	"offspringSharePredator":  &offspringSharePredator,
+	"transmissionProbability": &transmissionProbability,
+	"diseaseMortality":        &diseaseMortality,
+	"captureProbability":      &captureProbability,
+	"alignmentWeight":         &alignmentWeight,
+	"cohesionWeight":          &cohesionWeight,
+	"separationWeight":        &separationWeight,
+	"traitMutationStrength":   &traitMutationStrength,
+	"foodAttractionPrey":      &foodAttractionPrey,
+	"predatorAversionPrey":    &predatorAversionPrey,
+	"decayRate":               &decayRate,
+	"nutrientSpread":          &nutrientSpread,
+	"minDietPreference":       &minDietPreference,
+	"nightLight":              &nightLight,
+	"lightAttenuation":        &lightAttenuation,
+	"referenceTemperature":    &referenceTemperature,
+	"q10":                     &q10,
+	"activationEnergy":        &activationEnergy,
+	"turnNoise":               &turnNoise,
+	"stepLength":              &stepLength,
+	"captureRadius":           &captureRadius,
+	"feedingRadius":           &feedingRadius,
+	"interactionRadius":       &interactionRadius,
+	"exclusionRadius":         &exclusionRadius,
+}
+
+var stringParameters = map[string]*string{
+	"energyTransferRule":       &energyTransferRule,
+	"behaviourPrey":            &behaviourPrey,
+	"behaviourPredator":        &behaviourPredator,
+	"reproductionModePrey":     &reproductionModePrey,
+	"reproductionModePredator": &reproductionModePredator,
+	"crossoverRule":            &crossoverRule,
+	"thermalRule":              &thermalRule,
+}
+
+// IntRange is the smallest and largest value an integer parameter may take.
+type IntRange struct {
+	min int
+	max int
+}
+
+// AtLeast() is the IntRange of an integer parameter with no upper bound.
+func AtLeast(min int) IntRange {
+	return IntRange{min: min, max: math.MaxInt32}
+}
+
+// the values every integer parameter may take. a threshold, speed or length of time of 0 would stop the organisms or the day,
+// so those start at 1 like the mutated traits do, see InheritTraits().
+var intParameterRanges = map[string]IntRange{
+	"maxEnergy":               AtLeast(1),
+	"energyGainedPerPlankton": AtLeast(0),
+	"energyPerPrey":           AtLeast(0),
+	"planktonEnergy":          AtLeast(0),
+	"energyThresholdPrey":     AtLeast(1),
+	"ageThresholdPrey":        AtLeast(1),
+	"costOfLivingPrey":        AtLeast(0),
+	"speedPrey":               AtLeast(1),
+	"energyThresholdPredator": AtLeast(1),
+	"ageThresholdPredator":    AtLeast(1),
+	"costOfLivingPredator":    AtLeast(0),
+	"speedPredator":           AtLeast(1),
+	"handlingTimePredator":    AtLeast(0),
+	"satiationCapPredator":    AtLeast(0),
+	"digestionRatePredator":   AtLeast(0),
+	"infectionEnergyDrain":    AtLeast(0),
+	"recoveryTime":            AtLeast(0),
+	"visionRadiusPrey":        AtLeast(0),
+	"visionRadiusPredator":    AtLeast(0),
+	"chaseEnergyCostPrey":     AtLeast(0),
+	"chaseEnergyCostPredator": AtLeast(0),
+	"matingCostPrey":          AtLeast(0),
+	"matingCostPredator":      AtLeast(0),
+	"stepCostPrey":            AtLeast(0),
+	"stepCostPredator":        AtLeast(0),
+	"verticalMoveCost":        AtLeast(0),
+	"numLayers":               AtLeast(1),
+	"dayLength":               AtLeast(1),
+}
+
+// the values every string parameter may take, the ones the code that reads it accepts.
+var stringParameterValues = map[string][]string{
+	"energyTransferRule":       {"fixed", "fraction", "cappedFraction"},
+	"behaviourPrey":            {"randomWalk", "hunting"},
+	"behaviourPredator":        {"randomWalk", "hunting"},
+	"reproductionModePrey":     {"asexual", "sexual"},
+	"reproductionModePredator": {"asexual", "sexual"},
+	"crossoverRule":            {"uniform", "onePoint", "blend"},
+	"thermalRule":              {"none", "q10", "arrhenius"},
+}
+
+var boolParameters = map[string]*bool{
+	"flockingPrey":         &flockingPrey,
+	"diseaseEnabled":       &diseaseEnabled,
+	"decompositionEnabled": &decompositionEnabled,
+	"speedEvolvesPrey":     &speedEvolvesPrey,
+	"speedEvolvesPredator": &speedEvolvesPredator,
+}
+
+// CheckParameter() returns an error if there is no global parameter called name, value can't be parsed according to its type,
+// or it isn't one of the values the parameter may take, see intParameterRanges and stringParameterValues.
+// it doesn't change the parameter, so a schedule can be checked before it runs, see CheckInterventionArguments().
+func CheckParameter(name, value string) error {
+	var err error
+	if _, ok := intParameters[name]; ok {
+		var number int
+		number, err = strconv.Atoi(value)
+		limits := intParameterRanges[name]
+		if err == nil && number < limits.min {
+			return errors.New("invalid value " + value + " for parameter " + name + ". should be at least " + strconv.Itoa(limits.min))
+		} else if err == nil && number > limits.max {
+			return errors.New("invalid value " + value + " for parameter " + name + ". should be at most " + strconv.Itoa(limits.max))
+		}
+	} else if _, ok := floatParameters[name]; ok {
+		_, err = strconv.ParseFloat(value, 64)
+	} else if _, ok := boolParameters[name]; ok {
+		_, err = strconv.ParseBool(value)
+	} else if _, ok := stringParameters[name]; ok {
+		allowed := stringParameterValues[name]
+		for _, allowedValue := range allowed {
+			if value == allowedValue {
+				return nil
+			}
+		}
+		return errors.New("invalid value " + value + " for parameter " + name + ". should be " + ListOfValues(allowed) + "!")
+	} else {
+		return errors.New("unknown parameter " + name)
+	}
+
+	if err != nil {
+		return errors.New("invalid value " + value + " for parameter " + name + ": " + err.Error())
+	}
+	return nil
+}
+
+// CheckParameters() panics if the current value of a global integer or string parameter isn't allowed, see CheckParameter().
+// main calls it before the simulation starts, so a bad setting stops the program at once rather than partway through the run.
+func CheckParameters() {
+	for name := range intParameters {
+		if err := CheckParameter(name, GetParameter(name)); err != nil {
+			panic(err.Error())
+		}
+	}
+	for name := range stringParameters {
+		if err := CheckParameter(name, GetParameter(name)); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// ListOfValues() lists values the way the error messages do: "a or b", or "a, b, or c".
+func ListOfValues(values []string) string {
+	if len(values) <= 2 {
+		return strings.Join(values, " or ")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + ", or " + values[len(values)-1]
+}
+
+// SetParameter() sets the global parameter called name to value, parsed according to the type of the parameter.
+// it panics if there is no such parameter or value can't be parsed, see CheckParameter().
+func SetParameter(name, value string) {
+	if err := CheckParameter(name, value); err != nil {
+		panic(err.Error())
+	}
+
+	if pointer, ok := intParameters[name]; ok {
+		*pointer, _ = strconv.Atoi(value)
+	} else if pointer, ok := floatParameters[name]; ok {
+		*pointer, _ = strconv.ParseFloat(value, 64)
+	} else if pointer, ok := stringParameters[name]; ok {
+		*pointer = value
+	} else if pointer, ok := boolParameters[name]; ok {
+		*pointer, _ = strconv.ParseBool(value)
+	}
+}
+
+// GetParameter() returns the current value of the global parameter called name, formatted the way SetParameter() reads it.
+func GetParameter(name string) string {
+	if pointer, ok := intParameters[name]; ok {
+		return strconv.Itoa(*pointer)
+	} else if pointer, ok := floatParameters[name]; ok {
+		return strconv.FormatFloat(*pointer, 'g', -1, 64)
+	} else if pointer, ok := stringParameters[name]; ok {
+		return *pointer
+	} else if pointer, ok := boolParameters[name]; ok {
+		return strconv.FormatBool(*pointer)
+	}
+	panic("unknown parameter " + name)
+}
+
+// SetParameters() sets every parameter in values, see SetParameter().
+// Output: the values the parameters had before, so they can be put back with another call to SetParameters()
+func SetParameters(values map[string]string) map[string]string {
+	previous := make(map[string]string)
+	for name, value := range values {
+		previous[name] = GetParameter(name)
+		SetParameter(name, value)
+	}
+	return previous
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+)
+
+// PreyDirectionWeights() returns the probability weights currentPrey uses to choose its next gene index.
+// Without sensing (a visionRadius trait of 0) or flocking the weights are just the genome. Otherwise the weight of every gene is multiplied by exp(score),
+// where score adds up how well the direction that gene would move the prey lines up with each thing it reacts to:
+// food within the vision radius pulls the prey towards it (foodAttraction, weighted by how much its diet gets from the food), predators push it away (predatorAversion),
+// and with flockingPrey the neighbouring prey add alignment, cohesion and separation (see FlockingVectors()).
+// Input: the Board, the prey and its indices i, j
+// Output: 8 non-negative weights, one per gene index
+func PreyDirectionWeights(board Board, currentPrey *Prey, i, j int) [8]float64 {
+	weights := GenomeWeights(currentPrey.genome)
+
+	if currentPrey.traits.visionRadius <= 0 && !flockingPrey {
+		return weights
+	}
+
+	var foodPull, predatorPull, heading, centre, crowding [2]float64
+	if currentPrey.traits.visionRadius > 0 {
+		foodPull, predatorPull = SenseSurroundings(board, i, j, currentPrey.traits.visionRadius, currentPrey.traits.diet)
+	}
+	if flockingPrey {
+		heading, centre, crowding = FlockingVectors(board, i, j, flockRadius)
+	}
+
+	return ApplyPulls(weights, currentPrey, TurnVectors(currentPrey.lastDirection), foodPull, predatorPull, heading, centre, crowding)
+}
+
+// ApplyPulls() multiplies the weight of every gene index of currentPrey by exp(score), see PreyDirectionWeights().
+// turns is the unit vector of the move every gene index makes: TurnVectors() on the lattice, and HeadingVectors() in the continuous engine.
+func ApplyPulls(weights [8]float64, currentPrey *Prey, turns [8][2]float64, foodPull, predatorPull, heading, centre, crowding [2]float64) [8]float64 {
+	for idx, turn := range turns {
+		score := currentPrey.traits.foodAttraction*Dot(turn, foodPull) - currentPrey.traits.predatorAversion*Dot(turn, predatorPull)
+		score += alignmentWeight*Dot(turn, heading) + cohesionWeight*Dot(turn, centre) - separationWeight*Dot(turn, crowding)
+		weights[idx] *= math.Exp(score)
+	}
+
+	return weights
+}
+
+// TurnVectors() is the unit vector of the lattice direction (see deltas) every gene index turns to from lastDirection.
+func TurnVectors(lastDirection int) [8][2]float64 {
+	var turns [8][2]float64
+	for idx := range turns {
+		moveDeltas := deltas[(lastDirection+idx)%8]
+		length := math.Sqrt(float64(moveDeltas.row*moveDeltas.row + moveDeltas.col*moveDeltas.col))
+		turns[idx] = [2]float64{float64(moveDeltas.row) / length, float64(moveDeltas.col) / length}
+	}
+	return turns
+}
+
+// SenseSurroundings() scans every Unit within radius (Chebyshev distance, wrapping around the edges) of Unit i, j.
+// Output: two (row, col) vectors, pointing towards the food and towards the predators that were seen.
+// Each thing seen adds a unit vector towards it, divided by its distance, so closer things pull harder. Food pulls in proportion to DietEfficiency() of diet.
+func SenseSurroundings(board Board, i, j, radius int, diet []float64) ([2]float64, [2]float64) {
+	var foodPull, predatorPull [2]float64
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+
+	for deltaRow := -radius; deltaRow <= radius; deltaRow++ {
+		for deltaCol := -radius; deltaCol <= radius; deltaCol++ {
+			if deltaRow == 0 && deltaCol == 0 {
+				continue
+			}
+
+			seenRow, seenCol := GetIndex(i, deltaRow, numRows), GetIndex(j, deltaCol, numCols)
+			seenFood := board.FoodAt(seenRow, seenCol)
+			seenPredator := board.PredatorAt(seenRow, seenCol)
+			if !seenFood.isPresent && seenPredator == nil {
+				continue
+			}
+
+			pullRow, pullCol := PullTowards(float64(deltaRow), float64(deltaCol))
+
+			if seenFood.isPresent {
+				efficiency := DietEfficiency(diet, seenFood.foodType)
+				foodPull[0] += efficiency * pullRow
+				foodPull[1] += efficiency * pullCol
+			}
+			if seenPredator != nil {
+				predatorPull[0] += pullRow
+				predatorPull[1] += pullCol
+			}
+		}
+	}
+
+	return foodPull, predatorPull
+}
+
+// PullTowards() is the unit vector towards something deltaRow, deltaCol away, divided by the distance to it.
+func PullTowards(deltaRow, deltaCol float64) (float64, float64) {
+	distanceSquared := deltaRow*deltaRow + deltaCol*deltaCol
+	return deltaRow / distanceSquared, deltaCol / distanceSquared
+}
+
+// Dot() is the dot product of two (row, col) vectors.
+func Dot(a, b [2]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1]
+}
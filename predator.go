@@ -1,140 +1,206 @@
 package main
 
-import (
-	"math/rand"
-)
-
 // UpdatePredator is a Predator method which will take a Predator input and update the position, initiate eating, reproduction, and age accordingly
-func (shark *Predator) UpdatePredator(currEco *Ecosystem, i, j, curGen int) {
+func (shark *Predator) UpdatePredator(site Site, curGen int) {
 	// note we have moved the shark this timestep/generation
 	shark.lastGenUpdated = curGen
-	numRows := currEco.CountRows()
-	numCols := currEco.CountCols()
 
-	if shark.Organism.energy <= 0 {
-		(*currEco)[i][j].predator = nil
+	if !shark.Dies(site) {
+		// handle and digest earlier kills
+		shark.Digest()
 
-	} else {
 		//4. Reproduction
-		if shark.CheckAge(ageThresholdPredator) && shark.CheckEnergy(energyThresholdPredator) {
-
-			freeUnits := GetAvailableUnits(currEco, i, j)
+		thermalFactor := site.ThermalFactor()
+		if shark.IsMature(thermalFactor) {
+			site.Breed()
+		}
 
-			if len(freeUnits) != 0 {
-				var babyShark Predator
-				deltaX, deltaY := pickUnit(&freeUnits)
-				newI := GetIndex(i, deltaX, numRows)
-				newJ := GetIndex(j, deltaY, numCols)
-				(*currEco)[newI][newJ].predator = &babyShark
-				shark.Reproduce(&babyShark)
+		// the basal metabolic cost is paid once per generation, however far the shark moves. it grows with the local temperature
+		metabolicCost := ScaledCost(shark.traits.metabolism, thermalFactor)
+		shark.energy -= metabolicCost
+		curStats.predLedger.basal += metabolicCost
 
+		//1. Update POSITION AND ENERGY first if energy is allowed
+		// The shark moves and feeds one step at a time, up to its speed trait. it stops early if it runs out of energy or can't move
+		for step := 0; step < shark.traits.speed && shark.energy > 0; step++ {
+			if !site.Step() {
+				break
 			}
 		}
 
-		//1. Update POSITION AND ENERGY first if energy is allowed
-		// This UpdatePosition will scan through all 7 units, give a list of available units, and use GENOME to update
-		//	We prioritize the GENOME instead of the fish
-		// This function will UpdatePredatorPosition while returning the new index
+		//3. AGE
+		shark.UpdateAge() //Just add one
+
+	}
+}
 
-		deltaRow, deltaCol, newDirection, geneIndex, newR, newC := shark.UseGenomeToMove(currEco, i, j)
+// Dies removes the shark from its site if it has run out of energy or dies of old age (see DiesOfOldAge()), and counts its death.
+// a shark that dies of old age leaves its carcass behind. Output: whether the shark died
+func (shark *Predator) Dies(site Site) bool {
+	if shark.energy <= 0 {
+		shark.Starve(site)
+		return true
+	}
 
-		isMoving := deltaRow != 0 || deltaCol != 0
-		shark.DecreaseEnergy(geneIndex, isMoving)
+	if mortalityPredator.DiesOfOldAge(shark.age) {
+		site.LeaveCarcass(shark.energy)
+		site.Remove()
+		curStats.predDiedOfAge++
+		curStats.predLedger.lostAtDeath += shark.energy
+		return true
+	}
+	return false
+}
 
-		if shark.energy > 0 {
-			(*currEco)[newR][newC].predator = shark
-			(*currEco)[newR][newC].predator.lastDirection = newDirection
-			(*currEco)[i][j].predator = nil // remove the original pointer
-		}
+// Starve removes the shark, which has run out of energy, from its site and counts it as starved.
+func (shark *Predator) Starve(site Site) {
+	site.Remove()
+	curStats.predStarved++
+	curStats.predLedger.lostAtDeath += shark.energy
+}
 
-		// 2. FEEDING:
-		// Check to eat fish or not
-		shark.FeedShark(currEco, newR, newC)
+// Breed places a newborn of the shark in a free neighbour of Unit i, j, if there is one. in sexual mode the shark also needs an eligible neighbour to mate with.
+// the caller checks that the shark itself may reproduce.
+// Output: the mate, or nil in asexual mode or if no newborn was placed
+func (shark *Predator) Breed(board Board, i, j int) *Predator {
+	freeUnits := GetAvailableUnits(board, i, j, true)
 
-		//3. AGE
-		shark.UpdateAge() //Just add one
+	var mate *Predator
+	if IsSexual(reproductionModePredator) {
+		mate = shark.FindMate(board, i, j)
+	}
+
+	if len(freeUnits) == 0 || (mate == nil && IsSexual(reproductionModePredator)) {
+		return nil
+	}
 
+	var babyShark Predator
+	deltaX, deltaY := pickUnit(&freeUnits)
+	newI := GetIndex(i, deltaX, board.CountRows())
+	newJ := GetIndex(j, deltaY, board.CountCols())
+	board.SetPredator(newI, newJ, &babyShark)
+	if mate != nil {
+		shark.ReproduceSexually(mate, &babyShark)
+	} else {
+		shark.Reproduce(&babyShark)
 	}
+	board.Born(newI, newJ, true)
+	return mate
 }
 
-// UseGenomeToMovePredator() uses the genome to decide the next location of the organism in a probabilistic manner
-func (shark *Predator) UseGenomeToMove(currentEcosystem *Ecosystem, i, j int) (int, int, int, int, int, int) {
-	var moveDeltas OrderedPair
-	var geneIndex, newDirection, newI, newJ int
-	currentPredator := (*currentEcosystem)[i][j].predator
-	numTries := 0
+// MovePredator moves the shark one step from Unit i, j with its behaviour, behaviourPredator, and lets it eat the prey it lands on.
+// a shark that runs out of energy on the way stays where it was, and doesn't eat.
+// Output: the indices of the Unit the shark is in afterwards, and whether it tried to move
+func (shark *Predator) MovePredator(board Board, i, j int) (int, int, bool) {
+	var deltaRow, deltaCol, newDirection, geneIndex, newR, newC int
+	if behaviourPredator == "hunting" {
+		deltaRow, deltaCol, newDirection, geneIndex, newR, newC = shark.Hunt(board, i, j)
+	} else if behaviourPredator == "randomWalk" {
+		deltaRow, deltaCol, newDirection, geneIndex, newR, newC = movementPredator.Move(board, shark, i, j)
+	} else {
+		panic("invalid behaviourPredator string inputted. should be randomWalk or hunting!")
+	}
 
-	// 20 is the threshold for max number of tries we get to reselect a gene for movement
-	// if numberTries >= 20 and isFreeUnitFlag is still false
-	// the prey doesn't move
-	for !shark.isFreeUnit(currentEcosystem, i, j) && numTries < 20 {
-		r := rand.Float64()
-		geneIndex = 0
-		runningSum := 0.0
-		for idx, gene := range shark.genome {
-			runningSum += float64(gene)
-			if runningSum >= r {
-				geneIndex = idx
-				break
-			}
-		}
-		newDirection := (shark.lastDirection + geneIndex) % 8
-		moveDeltas = deltas[newDirection]
-		numRows := currentEcosystem.CountRows()
-		numCols := currentEcosystem.CountCols()
-		newI = GetIndex(i, moveDeltas.row, numRows)
-		newJ = GetIndex(j, moveDeltas.col, numCols)
+	isMoving := deltaRow != 0 || deltaCol != 0
+	shark.DecreaseEnergy(geneIndex, CellsMoved(deltaRow, deltaCol))
 
-		// This check if the unit is free or not
-		numTries += 1
+	if shark.energy <= 0 {
+		return i, j, isMoving
 	}
-	// if numTries >= 20 and still haven't find a free unit, we don't move
-	if !shark.isFreeUnit(currentEcosystem, i, j) {
-		geneIndex = 0
-		newDirection = currentPredator.lastDirection
-		moveDeltas.row, moveDeltas.col = 0, 0
+
+	if isMoving {
+		board.SetPredator(i, j, nil) // remove the original pointer
 	}
+	board.SetPredator(newR, newC, shark)
+	shark.lastDirection = newDirection
+
+	// 2. FEEDING:
+	// Check to eat fish or not
+	shark.FeedShark(board, newR, newC)
+
+	return newR, newC, isMoving
+}
 
-	//lastDirection will be updated with my new direction
-	return moveDeltas.row, moveDeltas.col, newDirection, geneIndex, newI, newJ
+// isFreeUnit checks whether the shark can move to Unit i, j. a shark that can't eat mustn't land on a prey, since they'd share the Unit,
+// and no shark can enter a refuge
+func (shark *Predator) isFreeUnit(board Board, i, j int) bool {
+	return board.PredatorAt(i, j) == nil && board.RefugeAt(i, j) == nil && (board.PreyAt(i, j) == nil || shark.CanEat())
 }
 
-func (shark *Predator) isFreeUnit(currEco *Ecosystem, i, j int) bool {
-	return (*currEco)[i][j].predator == nil
+// CanEat checks whether the shark is done handling its last kill and has room in its gut
+func (shark *Predator) CanEat() bool {
+	return shark.handlingTime == 0 && (satiationCapPredator == 0 || shark.gutContents < satiationCapPredator)
 }
 
-func (shark *Predator) FeedShark(currEco *Ecosystem, x, y int) {
-	if (*currEco)[x][y].prey != nil {
-		(*currEco)[x][y].prey = nil
-		shark.IncreaseEngeryAfterMeal() //increase energy after eating a fish
+// Digest counts down the handling time of the last kill and digests digestionRatePredator prey from the gut. it is called once per generation
+func (shark *Predator) Digest() {
+	if shark.handlingTime > 0 {
+		shark.handlingTime -= 1
+	}
+	shark.gutContents -= digestionRatePredator
+	if shark.gutContents < 0 {
+		shark.gutContents = 0
+	}
+}
+
+// StartHandling puts a kill in the shark's gut and starts its handling time. Digest() counts the handling time down at the start of every
+// generation, before the shark feeds, so it is set one higher to keep the shark from eating for handlingTimePredator whole generations after this one
+func (shark *Predator) StartHandling() {
+	if handlingTimePredator > 0 {
+		shark.handlingTime = handlingTimePredator + 1
+	}
+	shark.gutContents += 1
+}
+
+func (shark *Predator) FeedShark(board Board, x, y int) {
+	if board.PreyAt(x, y) != nil && shark.CanEat() {
+		curStats.preyEaten++
+		if CountPreyNeighbours(board, x, y) > 0 {
+			curStats.schooledPreyEaten++
+		}
+		preyEnergy := board.PreyAt(x, y).energy
+		curStats.preyLedger.lostAtDeath += preyEnergy
+		board.SetPrey(x, y, nil)
+		gained := shark.IncreaseEngeryAfterMeal(preyEnergy) //increase energy after eating a fish
+		// whatever the shark didn't get out of the prey is left behind
+		board.LeaveCarcass(x, y, preyEnergy-gained)
+		shark.StartHandling()
 
 	}
 
 }
 
-func (shark *Predator) IncreaseEngeryAfterMeal() {
-	shark.Organism.energy += 1
+// IncreaseEngeryAfterMeal adds the energy the shark gets from eating a prey that held preyEnergy, see EnergyTransferred(), and returns it
+func (shark *Predator) IncreaseEngeryAfterMeal(preyEnergy int) int {
+	gained := EnergyTransferred(shark.Organism.energy, preyEnergy, energyPerPrey)
+	shark.Organism.energy += gained
+	curStats.predLedger.ingested += gained
+	return gained
 }
 
-func GetAvailableUnits(currEco *Ecosystem, r, c int) []int {
+// GetAvailableUnits lists the neighbours of Unit r, c where a newborn can be placed, as the indices GetIndices() turns back into deltas.
+// a newborn predator needs a Unit without a predator, a newborn prey needs a Unit without either
+func GetAvailableUnits(board Board, r, c int, IsThisAPredator bool) []int {
 	var units []int
 	var n int
+	numRows := board.CountRows()
+	numCols := board.CountCols()
 	for i := r - 1; i <= r+1; i++ {
 		i_updated := -1
 
 		if i < 0 {
-			i_updated = len(*currEco) - 1
+			i_updated = numRows - 1
 		}
-		if i == len(*currEco) {
+		if i == numRows {
 			i_updated = 0
 		}
 
 		for j := c - 1; j <= c+1; j++ {
 			j_updated := -1
 			if j < 0 {
-				j_updated = len((*currEco)[0]) - 1
+				j_updated = numCols - 1
 			}
-			if j == len((*currEco)[0]) {
+			if j == numCols {
 				j_updated = 0
 			}
 
@@ -147,8 +213,8 @@ func GetAvailableUnits(currEco *Ecosystem, r, c int) []int {
 				i_updated = i
 			}
 
-			if IsItAvailable((*currEco)[i_updated][j_updated], true) {
-				n = GetUnit(r, c, i_updated, j_updated, len(*currEco))
+			if IsItAvailable(board, i_updated, j_updated, IsThisAPredator) {
+				n = GetUnit(r, c, i_updated, j_updated, numRows)
 				units = append(units, n)
 			}
 
@@ -159,16 +225,20 @@ func GetAvailableUnits(currEco *Ecosystem, r, c int) []int {
 
 func (shark *Predator) Reproduce(babyShark *Predator) {
 	//Already check age and energy!!!!
-	shark.Organism.age = 0
-	babyShark.Organism.energy = shark.Organism.energy / 2
-	shark.Organism.energy /= 2
+	shark.Organism.timeSinceReproduction = 0
+	babyShark.Organism.energy = int(float64(shark.Organism.energy) * shark.traits.offspringShare)
+	shark.Organism.energy -= babyShark.Organism.energy
+	curStats.predLedger.toOffspring += babyShark.Organism.energy
 	babyShark.Organism.genome = shark.Organism.genome // Check if the array needs to be copied manually.
+	babyShark.Organism.traits = InheritTraits(shark.Organism.traits, true)
+	babyShark.Organism.verticalGenome = InheritVerticalGenome(shark.Organism.verticalGenome, shark.Organism.verticalGenome)
 	UpdateDirection(&shark.Organism, &babyShark.Organism)
-	UpdateGenome(&babyShark.Organism)
+	MutateGenome(&babyShark.Organism, mutationPredator)
 }
 
+// CheckAge checks whether it has been at least threshold generations since the shark was born or last reproduced
 func (shark *Predator) CheckAge(threshold int) bool {
-	return shark.Organism.age >= threshold
+	return shark.Organism.timeSinceReproduction >= threshold
 }
 
 func (shark *Predator) CheckEnergy(threshold int) bool {
@@ -177,25 +247,32 @@ func (shark *Predator) CheckEnergy(threshold int) bool {
 
 func (shark *Predator) UpdateAge() {
 	shark.Organism.age += 1
+	shark.Organism.timeSinceReproduction += 1
 }
 
-func (shark *Predator) DecreaseEnergy(geneIndex int, isMoving bool) {
-	shark.energy -= costOfLivingPredator
-
-	if isMoving {
-		shark.energy -= energyCosts[geneIndex]
+// DecreaseEnergy charges the shark for turning by geneIndex, see energyCosts, and stepCostPredator for every cell it moved
+func (shark *Predator) DecreaseEnergy(geneIndex int, cellsMoved int) {
+	if cellsMoved > 0 {
+		cost := energyCosts[geneIndex] + stepCostPredator*cellsMoved
+		shark.energy -= cost
+		curStats.predLedger.moving += cost
 	}
 }
 
-func IsItAvailable(unit *Unit, IsThisAPredator bool) bool {
-	//Check if there is any predator
-	return unit.predator == nil
+func IsItAvailable(board Board, i, j int, IsThisAPredator bool) bool {
+	//Check if there is any predator. predators are never born in a refuge
+	if IsThisAPredator {
+		return board.PredatorAt(i, j) == nil && board.RefugeAt(i, j) == nil
+	}
+	// a prey would overwrite another prey, and a full refuge has no room for the newborn
+	return board.PredatorAt(i, j) == nil && board.PreyAt(i, j) == nil && board.RefugeAt(i, j).HasRoom(board, nil)
 }
 
 func GetUnit(r, c, i, j, n int) int {
 	var unit int
-	rowDelta := r - i
-	colDelta := c - j
+	// the delta from Unit r, c to its neighbour i, j, the way GetIndices() returns it
+	rowDelta := i - r
+	colDelta := j - c
 
 	//edge case
 	if rowDelta < -1 { //first row
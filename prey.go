@@ -2,7 +2,6 @@ package main
 
 import (
 	"math"
-	"math/rand"
 )
 
 //Set a constant dictionary where keys are the directionIndex and the values are the orderedPair with corresponding deltaX and deltaY
@@ -29,145 +28,159 @@ import (
 // 	7: -1,
 // }
 
-// Input: currentUnit is a pointer to a unit, currentEcosystem is a pointer to the ecosystem, i and j are the indices of the location of the unit we are about to move, curGen is the number of generations of the unit we are about to move.
-// Output: none, operates on pointers
-func MovePrey(currentEcosystem *Ecosystem, i, j int) {
-	currentUnit := (*currentEcosystem)[i][j]
-	currentPrey := currentUnit.prey
+// Input: board is the Board the prey lives on, i and j are the indices of the location of the prey we are about to move.
+// Output: the indices of the Unit the prey ended up in
+func MovePrey(board Board, i, j int) (int, int) {
+	currentPrey := board.PreyAt(i, j)
 
-	deltaX, deltaY, newDirection, geneIndex, newI, newJ := UseGenomeToMovePrey(currentEcosystem, currentPrey, i, j)
+	var deltaX, deltaY, newDirection, geneIndex, newI, newJ int
+	if behaviourPrey == "hunting" {
+		deltaX, deltaY, newDirection, geneIndex, newI, newJ = HuntFood(board, currentPrey, i, j)
+	} else if behaviourPrey == "randomWalk" {
+		deltaX, deltaY, newDirection, geneIndex, newI, newJ = movementPrey.Move(board, currentPrey, i, j)
+	} else {
+		panic("invalid behaviourPrey string inputted. should be randomWalk or hunting!")
+	}
 
-	// energy decreases based on how drastic the change in direction is for the movement
+	// energy decreases based on how drastic the change in direction is for the movement, and on how many cells were crossed
 	// if at least one of deltaX or deltaY is not equal to 0, we move the prey
-	isMoving := deltaX != 0 || deltaY != 0
-	currentPrey.DecreaseEnergy(geneIndex, isMoving)
+	currentPrey.DecreaseEnergy(geneIndex, CellsMoved(deltaX, deltaY))
 
-	currentUnit.prey = nil
+	board.SetPrey(i, j, nil)
 
 	// check if energy level > 0
 	// if it is not, update direction
 	if currentPrey.energy > 0 {
 
 		// when deltaX and deltaY == 0, currentPrey stay at unit [i, j]
-		(*currentEcosystem)[newI][newJ].prey = currentPrey
+		board.SetPrey(newI, newJ, currentPrey)
 
 		// comes after moving the prey
 		currentPrey.lastDirection = newDirection
 
-	}
+		if CheckIfEats(board.FoodAt(newI, newJ), currentPrey) {
+			currentPrey.FeedOrganism(board.FoodAt(newI, newJ))
+			board.RemoveFood(newI, newJ)
+		}
 
-	if CheckIfEats((*currentEcosystem)[newI][newJ], currentPrey) {
-		currentPrey.FeedOrganism((*currentEcosystem)[newI][newJ])
+	} else {
+		// the prey ran out of energy moving, so it starved. it's gone, so it doesn't get to eat
+		StarvePrey(&LatticeSite{board: board, i: i, j: j}, currentPrey)
 	}
+
+	return newI, newJ
 }
 
-func CheckIfEats(currentUnit *Unit, currentPrey *Prey) bool {
-	return currentUnit.food.isPresent && (currentPrey.energy < maxEnergy)
+func CheckIfEats(someFood Food, currentPrey *Prey) bool {
+	return currentPrey.WillEat(someFood) && (currentPrey.energy < maxEnergy)
 }
 
-func (currentPrey Prey) FeedOrganism(currentUnit *Unit) {
-	currentUnit.food.isPresent = false
-	currentPrey.energy += energyGainedPerPlankton
+// FeedOrganism() gives the prey the energy of someFood, which the caller takes off the board. the food is worth more or less to the prey depending on its diet, see DietEfficiency()
+func (currentPrey *Prey) FeedOrganism(someFood Food) {
+	curStats.foodEaten++
+	fixedGain, foodEnergy := FoodEnergy(someFood.foodType)
+	efficiency := DietEfficiency(currentPrey.traits.diet, someFood.foodType)
+	gained := EnergyTransferred(currentPrey.energy, int(efficiency*float64(foodEnergy)), int(efficiency*float64(fixedGain)))
+	currentPrey.energy += gained
+	curStats.preyLedger.ingested += gained
 }
 
-// cannot move to unit where there's shark (predator)
-func UseGenomeToMovePrey(currentEcosystem *Ecosystem, currentPrey *Prey, i, j int) (int, int, int, int, int, int) {
-	var moveDeltas OrderedPair
-	var geneIndex, newDirection, newI, newJ int
-	isFreeUnitFlag := false
-	numTries := 0
-
-	// 20 is the threshold for max number of tries we get to reselect a gene for movement
-	// if numberTries >= 20 and isFreeUnitFlag is still false
-	// the prey doesn't move
-	for !isFreeUnitFlag && numTries < 20 {
-		r := rand.Float64()
-		geneIndex = 0
-		runningSum := 0.0
-		for idx, gene := range currentPrey.genome {
-			runningSum += float64(gene)
-			if runningSum >= r {
-				geneIndex = idx
-				break
-			}
-		}
-		newDirection := (currentPrey.lastDirection + geneIndex) % 8
-		moveDeltas = deltas[newDirection]
-		numRows := currentEcosystem.CountRows()
-		numCols := currentEcosystem.CountCols()
-		newI = GetIndex(i, moveDeltas.row, numRows)
-		newJ = GetIndex(j, moveDeltas.col, numCols)
-
-		isFreeUnitFlag = isFreeUnit(currentEcosystem, i, j)
-		numTries += 1
-	}
-	// if numTries >= 20 and still haven't find a free unit, we don't move
-	if !isFreeUnitFlag {
-		geneIndex = 0
-		newDirection = currentPrey.lastDirection
-		moveDeltas.row, moveDeltas.col = 0, 0
+// ChooseGeneIndex picks a gene index at random with probability proportional to its weight.
+// weights don't need to add up to 1, e.g. a genome that has been reweighted by perception.
+func ChooseGeneIndex(weights [8]float64) int {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
 	}
 
-	//lastDirection will be updated with my new direction
-	return moveDeltas.row, moveDeltas.col, newDirection, geneIndex, newI, newJ
+	r := rng.Float64() * total
+	runningSum := 0.0
+	for idx, weight := range weights {
+		runningSum += weight
+		if runningSum >= r && weight > 0 {
+			return idx
+		}
+	}
+	return 0
 }
 
-func (currentPrey *Prey) DecreaseEnergy(geneIndex int, isMoving bool) {
-	currentPrey.energy -= costOfLivingPrey
+// GenomeWeights() is the weight of every gene index of genome, for ChooseGeneIndex(): the gene itself.
+func GenomeWeights(genome [8]Gene) [8]float64 {
+	var weights [8]float64
+	for idx, gene := range genome {
+		weights[idx] = float64(gene)
+	}
+	return weights
+}
 
+func (currentPrey *Prey) DecreaseEnergy(geneIndex int, cellsMoved int) {
 	// if prey needs to be moved since either deltaX or deltaY or both are not equal to 0
-	// we decrease the energy based on the geneIndex
-	if isMoving {
-		currentPrey.energy -= energyCosts[geneIndex]
+	// we decrease the energy based on the geneIndex, plus stepCostPrey for every cell
+	if cellsMoved > 0 {
+		cost := energyCosts[geneIndex] + stepCostPrey*cellsMoved
+		currentPrey.energy -= cost
+		curStats.preyLedger.moving += cost
 	}
 
 }
 
 // check if unit (i, j) is unoccupied by a predator or another prey
-func isFreeUnit(currentEcosystem *Ecosystem, i, j int) bool {
-	if (*currentEcosystem)[i][j].prey == nil && (*currentEcosystem)[i][j].predator == nil {
+func isFreeUnit(board Board, i, j int) bool {
+	if board.PreyAt(i, j) == nil && board.PredatorAt(i, j) == nil {
 		return true
 	} else {
 		return false
 	}
 }
 
+// CellsMoved() is the number of cells crossed by a move of deltaRow, deltaCol, counting diagonal steps as one cell
+func CellsMoved(deltaRow, deltaCol int) int {
+	if Abs(deltaRow) > Abs(deltaCol) {
+		return Abs(deltaRow)
+	}
+	return Abs(deltaCol)
+}
+
 // pass in the row, column indices and the delta for movement
 // return new row, column indices within the boundary
 // boundary is the numRow and numCol of the ecosystem board
+// delta can be larger than the board, e.g. for an organism that has evolved a large visionRadius
 func GetIndex(index, delta, boundary int) int {
-	newIndex := index + delta
+	newIndex := (index + delta) % boundary
 	if newIndex < 0 {
 		newIndex = boundary + newIndex
-	} else if newIndex >= boundary {
-		newIndex = newIndex % boundary
 	}
 	return newIndex
 }
 
-// UpdateAge takes in a pointer to a prey object, updates it age by incrementing it by one and then returns it.
+// UpdateAge takes in a pointer to a prey object, updates it age and time since reproduction by incrementing them by one and then returns it.
 func UpdateAgePrey(p *Prey) {
 	p.Organism.age += 1
+	p.Organism.timeSinceReproduction += 1
 }
 
-// UpdateAge takes in a pointer to a prey object, updates it age by incrementing it by one and then returns it.
+// UpdateAge takes in a pointer to a prey object, updates it age and time since reproduction by incrementing them by one and then returns it.
 func UpdateAgePredator(p *Predator) {
 	p.Organism.age += 1
+	p.Organism.timeSinceReproduction += 1
 }
 
 func ReproducePrey(parent, child *Prey) {
 	//This function will only be called if the age and energy and requirements are met. Check these requirements before calling this function.
-	parent.Organism.age = 0
-	child.Organism.energy = parent.Organism.energy / 2
-	parent.Organism.energy /= 2
+	parent.Organism.timeSinceReproduction = 0
+	child.Organism.energy = int(float64(parent.Organism.energy) * parent.traits.offspringShare)
+	parent.Organism.energy -= child.Organism.energy
+	curStats.preyLedger.toOffspring += child.Organism.energy
 	child.Organism.genome = parent.Organism.genome // Check if the array needs to be copied manually.
+	child.Organism.traits = InheritTraits(parent.Organism.traits, false)
+	child.Organism.verticalGenome = InheritVerticalGenome(parent.Organism.verticalGenome, parent.Organism.verticalGenome)
 	UpdateDirection(&parent.Organism, &child.Organism)
-	UpdateGenome(&child.Organism)
+	MutateGenome(&child.Organism, mutationPrey)
 }
 
 // UpdateDirection updates the direction of that the child is moving in based on the parents genome and direction of movement
 func UpdateDirection(parent, child *Organism) {
-	r := rand.Float64()
+	r := rng.Float64()
 	var sum Gene
 	index := 0
 	for i := range parent.genome {
@@ -181,10 +194,10 @@ func UpdateDirection(parent, child *Organism) {
 	child.lastDirection = (parent.lastDirection + index) % 8
 }
 
-// UpdateGenome updates the genome of the child based on the last known movement.
-func UpdateGenome(currentOrganism *Organism) {
+// UpdateGenome updates the genome of the child based on the last known movement. This is the original mutation operator, see LegacyMutation.
+// The gene of the child's lastDirection is boosted by delta times itself and the other genes shrink to make room, so the genome may need renormalising afterwards.
+func UpdateGenome(currentOrganism *Organism, delta float64) {
 	currentDirection := currentOrganism.lastDirection
-	delta := 0.8
 	for i := range currentOrganism.genome {
 		if i != currentDirection {
 			if currentOrganism.genome[i]-Gene(delta)*currentOrganism.genome[currentDirection] > 0 {
@@ -193,73 +206,122 @@ func UpdateGenome(currentOrganism *Organism) {
 		}
 	}
 	currentOrganism.genome[currentDirection] += Gene(delta) * currentOrganism.genome[currentDirection]
-	CheckGenome(currentOrganism.genome)
 }
 
-// CheckGenome checks that we have not exceeded 1 by summing the genes for a given input genome
+// CheckGenome checks that a given input genome is a valid probability distribution: no gene is negative and the genes add up to 1
 func CheckGenome(currentGenome [8]Gene) bool {
 	sum := Gene(0.0)
 	for i := range currentGenome {
+		if currentGenome[i] < 0 {
+			return false
+		}
 		sum += currentGenome[i]
 	}
 
-	result := true
-
-	// if the sum minus 1 rounded to nearest integer is less than 0, that would mean that the sum is under 0.5
-	// if the sum plus 1 rounded to the nearest integer is greater than or equal to 1, than means sum is over 1.5
-	if math.Round(float64(sum))-1 < 0 || math.Round(float64(sum))+1 >= 1 {
-		result = false
-	}
-
-	return result
+	// allow for floating point error in the sum
+	return math.Abs(float64(sum)-1) < 1e-6
 }
 func ReproducePredator(p *Predator) *Predator {
 	//This function will only be called if the age and energy and requirements are met. Check these requirements before calling this function.
 	var child Predator
-	p.Organism.age = 0
-	child.Organism.energy = p.Organism.energy / 2
-	p.Organism.energy /= 2
+	p.Organism.timeSinceReproduction = 0
+	child.Organism.energy = int(float64(p.Organism.energy) * p.traits.offspringShare)
+	p.Organism.energy -= child.Organism.energy
+	curStats.predLedger.toOffspring += child.Organism.energy
 	child.Organism.genome = p.Organism.genome // Check if the array needs to be copied manually.
+	child.Organism.traits = InheritTraits(p.Organism.traits, true)
+	child.Organism.verticalGenome = InheritVerticalGenome(p.Organism.verticalGenome, p.Organism.verticalGenome)
 	UpdateDirection(&p.Organism, &child.Organism)
-	UpdateGenome(&child.Organism)
+	MutateGenome(&child.Organism, mutationPredator)
 	return &child
 }
 
-func UpdatePrey(currentEcosystem *Ecosystem, i, j, currGen int) {
-	numRows := currentEcosystem.CountRows()
-	numCols := currentEcosystem.CountCols()
-	currentPrey := (*currentEcosystem)[i][j].prey
+// UpdatePrey() is one generation of the life of currentPrey at site, see Site.
+func UpdatePrey(site Site, currentPrey *Prey, currGen int) {
 	// note we have moved the prey this timestep/generation
 	currentPrey.lastGenUpdated = currGen
 
-	if currentPrey.Organism.energy <= 0 {
-		(*currentEcosystem)[i][j].prey = nil
+	if PreyDies(site, currentPrey) {
 		return
 	}
 
 	UpdateAgePrey(currentPrey)
 
-	if (*currentEcosystem)[i][j].prey.energy >= energyThresholdPrey && (*currentEcosystem)[i][j].prey.age >= ageThresholdPrey {
-		var babyPrey Prey
+	// the basal metabolic cost is paid once per generation, however far the prey moves. it grows with the local temperature
+	thermalFactor := site.ThermalFactor()
+	metabolicCost := ScaledCost(currentPrey.traits.metabolism, thermalFactor)
+	currentPrey.energy -= metabolicCost
+	curStats.preyLedger.basal += metabolicCost
 
-		freeUnits := GetAvailableUnits(currentEcosystem, i, j)
-
-		if len(freeUnits) != 0 {
-			deltaX, deltaY := pickUnit(&freeUnits)
-			newI := GetIndex(i, deltaX, numRows)
-			newJ := GetIndex(j, deltaY, numCols)
-			(*currentEcosystem)[newI][newJ].prey = &babyPrey
-			ReproducePrey(currentPrey, &babyPrey)
+	if currentPrey.IsMature(thermalFactor) {
+		site.Breed()
+	}
+	// the prey moves one step at a time, up to its speed trait. it stops early if it dies or can't move
+	for step := 0; step < currentPrey.traits.speed; step++ {
+		if !site.Step() {
+			break
 		}
+	}
+
+}
+
+// PreyDies() removes currentPrey from its site if it has run out of energy or dies of old age (see DiesOfOldAge()), and counts its death.
+// a prey that dies of old age leaves its carcass behind. Output: whether the prey died
+func PreyDies(site Site, currentPrey *Prey) bool {
+	if currentPrey.energy <= 0 {
+		StarvePrey(site, currentPrey)
+		return true
+	}
+
+	if mortalityPrey.DiesOfOldAge(currentPrey.age) {
+		site.LeaveCarcass(currentPrey.energy)
+		site.Remove()
+		curStats.preyDiedOfAge++
+		curStats.preyLedger.lostAtDeath += currentPrey.energy
+		return true
+	}
+	return false
+}
+
+// StarvePrey() removes currentPrey, which has run out of energy, from its site and counts it as starved.
+func StarvePrey(site Site, currentPrey *Prey) {
+	site.Remove()
+	curStats.preyStarved++
+	curStats.preyLedger.lostAtDeath += currentPrey.energy
+}
 
+// BreedPrey() places a newborn of currentPrey in a free neighbour of Unit i, j, if there is one. in sexual mode the prey also needs an eligible neighbour to mate with.
+// the caller checks that currentPrey itself may reproduce.
+// Output: the mate, or nil in asexual mode or if no newborn was placed
+func BreedPrey(board Board, currentPrey *Prey, i, j int) *Prey {
+	freeUnits := GetAvailableUnits(board, i, j, false)
+
+	var mate *Prey
+	if IsSexual(reproductionModePrey) {
+		mate = FindMatePrey(board, i, j)
+	}
+
+	if len(freeUnits) == 0 || (mate == nil && IsSexual(reproductionModePrey)) {
+		return nil
 	}
-	MovePrey(currentEcosystem, i, j)
 
+	var babyPrey Prey
+	deltaX, deltaY := pickUnit(&freeUnits)
+	newI := GetIndex(i, deltaX, board.CountRows())
+	newJ := GetIndex(j, deltaY, board.CountCols())
+	board.SetPrey(newI, newJ, &babyPrey)
+	if mate != nil {
+		ReproducePreySexually(currentPrey, mate, &babyPrey)
+	} else {
+		ReproducePrey(currentPrey, &babyPrey)
+	}
+	board.Born(newI, newJ, false)
+	return mate
 }
 
 func pickUnit(freeUnits *[]int) (r, c int) {
 	length := len(*freeUnits)
-	random := rand.Intn(length)
+	random := rng.Intn(length)
 	chosenUnit := (*freeUnits)[random]
 	return GetIndices(&chosenUnit)
 }
@@ -0,0 +1,63 @@
+package main
+
+// Refuge is a predator-free zone, e.g. a seagrass bed or a crevice. Predators can't enter its Units,
+// and it shelters at most capacity prey at a time (0 for no limit). MarkRefuges() links every Unit of the area to its Refuge.
+// If two refuges overlap, the later one in refuges owns the shared Units.
+type Refuge struct {
+	area     Region
+	capacity int
+	cells    []OrderedPair // the Units that belong to the refuge, filled in by MarkRefuges()
+}
+
+// MarkRefuges() sets the refuge of every Unit of someEcosystem that lies in one of refuges. It is called before any organism is placed.
+// every Ecosystem gets its own copy of refuges, so the Units of a refuge always belong to the same Ecosystem (see Patch).
+func MarkRefuges(someEcosystem *Ecosystem) {
+	ecosystemRefuges := make([]Refuge, len(refuges))
+	copy(ecosystemRefuges, refuges)
+	for k := range ecosystemRefuges {
+		ecosystemRefuges[k].cells = nil
+	}
+
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			(*someEcosystem)[i][j].refuge = nil
+			for k := range ecosystemRefuges {
+				if ecosystemRefuges[k].area.Contains(i, j) {
+					(*someEcosystem)[i][j].refuge = &ecosystemRefuges[k]
+				}
+			}
+			if (*someEcosystem)[i][j].refuge != nil {
+				(*someEcosystem)[i][j].refuge.cells = append((*someEcosystem)[i][j].refuge.cells, OrderedPair{i, j})
+			}
+		}
+	}
+}
+
+// HasRoom() checks whether one more prey fits in refuge. mover is the prey that wants to move in: it isn't counted, so it can move around inside a full refuge.
+// a nil refuge (a Unit outside every refuge) always has room. use nil as mover for a newborn or new prey.
+func (refuge *Refuge) HasRoom(board Board, mover *Prey) bool {
+	if refuge == nil || refuge.capacity == 0 {
+		return true
+	}
+	numSheltered := 0
+	for _, location := range refuge.cells {
+		sheltered := board.PreyAt(location.row, location.col)
+		if sheltered != nil && sheltered != mover {
+			numSheltered++
+		}
+	}
+	return numSheltered < refuge.capacity
+}
+
+// CountShelteredPrey() counts the prey that are inside a refuge.
+func CountShelteredPrey(someEcosystem *Ecosystem) int {
+	count := 0
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].refuge != nil && (*someEcosystem)[i][j].prey != nil {
+				count++
+			}
+		}
+	}
+	return count
+}
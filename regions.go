@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Region is a set of Units of an Ecosystem, e.g. a fishing ground or a marine protected area.
+type Region interface {
+	Contains(row, col int) bool
+}
+
+// Rectangle is the block of Units from (minRow, minCol) to (maxRow, maxCol), both corners included.
+type Rectangle struct {
+	minRow, minCol int
+	maxRow, maxCol int
+}
+
+// Mask marks the Units of a Region with true. it is usually loaded with LoadMaskFromFile.
+type Mask [][]bool
+
+func (rect Rectangle) Contains(row, col int) bool {
+	return rect.minRow <= row && row <= rect.maxRow && rect.minCol <= col && col <= rect.maxCol
+}
+
+// Contains is false outside of the mask, so a mask smaller than the Ecosystem only covers its top left corner.
+func (someMask Mask) Contains(row, col int) bool {
+	return row >= 0 && row < len(someMask) && col >= 0 && col < len(someMask[row]) && someMask[row][col]
+}
+
+// InAnyRegion() checks whether Unit row, col lies in at least one of regions.
+func InAnyRegion(regions []Region, row, col int) bool {
+	for _, someRegion := range regions {
+		if someRegion.Contains(row, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadMaskFromFile() reads a mask from a text file with one line per row of the Ecosystem.
+// '#' and '1' mark a Unit that is part of the mask, any other character marks one that isn't. Lines starting with // are skipped.
+func LoadMaskFromFile(filename string) Mask {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic("could not open mask file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	var newMask Mask
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		maskRow := make([]bool, len(line))
+		for col, character := range line {
+			maskRow[col] = character == '#' || character == '1'
+		}
+		newMask = append(newMask, maskRow)
+	}
+
+	if err := scanner.Err(); err != nil {
+		panic("could not read mask file " + filename + ": " + err.Error())
+	}
+
+	return newMask
+}
@@ -0,0 +1,142 @@
+package main
+
+// IsSexual() says whether reproductionMode is "sexual". it panics on anything other than "asexual" or "sexual".
+func IsSexual(reproductionMode string) bool {
+	if reproductionMode == "sexual" {
+		return true
+	} else if reproductionMode == "asexual" {
+		return false
+	}
+	panic("invalid reproductionMode string inputted. should be asexual or sexual!")
+}
+
+// IsMature() says whether someOrganism may reproduce: its energy has reached its energyThreshold, and its timeSinceReproduction its ageThreshold
+// scaled by thermalFactor (see ScaledAge()). every engine checks the organism and its mate with it.
+func (someOrganism *Organism) IsMature(thermalFactor float64) bool {
+	return someOrganism.energy >= someOrganism.traits.energyThreshold && someOrganism.timeSinceReproduction >= ScaledAge(someOrganism.traits.ageThreshold, thermalFactor)
+}
+
+// FindMatePrey() looks among the 8 neighbours of Unit i, j for another prey that is also mature, see IsMature().
+// Output: a randomly chosen eligible mate, or nil if there is none
+func FindMatePrey(board Board, i, j int) *Prey {
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+	currentPrey := board.PreyAt(i, j)
+	var mates []*Prey
+	for _, moveDeltas := range deltas {
+		row, col := GetIndex(i, moveDeltas.row, numRows), GetIndex(j, moveDeltas.col, numCols)
+		neighbour := board.PreyAt(row, col)
+		if neighbour != nil && neighbour != currentPrey && neighbour.IsMature(board.ThermalFactor(row, col)) {
+			mates = append(mates, neighbour)
+		}
+	}
+
+	if len(mates) == 0 {
+		return nil
+	}
+	return mates[rng.Intn(len(mates))]
+}
+
+// FindMate() looks among the 8 neighbours of Unit i, j for a predator that is also mature.
+// Output: a randomly chosen eligible mate, or nil if there is none
+func (shark *Predator) FindMate(board Board, i, j int) *Predator {
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+	var mates []*Predator
+	for _, moveDeltas := range deltas {
+		row, col := GetIndex(i, moveDeltas.row, numRows), GetIndex(j, moveDeltas.col, numCols)
+		neighbour := board.PredatorAt(row, col)
+		if neighbour != nil && neighbour != shark && neighbour.IsMature(board.ThermalFactor(row, col)) {
+			mates = append(mates, neighbour)
+		}
+	}
+
+	if len(mates) == 0 {
+		return nil
+	}
+	return mates[rng.Intn(len(mates))]
+}
+
+// ReproducePreySexually() makes child from parent and mate. Both parents pay matingCostPrey and give half their offspringShare of energy to the child,
+// so a child starts with about as much energy as an asexual one. The child genome is a crossover of both genomes (see Crossover()),
+// which is then mutated by mutationPrey. The heritable traits and the VerticalGenome are the parents' average plus mutation.
+func ReproducePreySexually(parent, mate, child *Prey) {
+	//This function will only be called if both parents meet the age and energy requirements. Check these requirements before calling this function.
+	parent.Organism.timeSinceReproduction = 0
+	mate.Organism.timeSinceReproduction = 0
+	parent.Organism.energy -= matingCostPrey
+	mate.Organism.energy -= matingCostPrey
+	curStats.preyLedger.mating += 2 * matingCostPrey
+
+	parentGift := int(float64(parent.Organism.energy) * parent.traits.offspringShare / 2)
+	mateGift := int(float64(mate.Organism.energy) * mate.traits.offspringShare / 2)
+	child.Organism.energy = parentGift + mateGift
+	parent.Organism.energy -= parentGift
+	mate.Organism.energy -= mateGift
+	curStats.preyLedger.toOffspring += child.Organism.energy
+
+	child.Organism.genome = Crossover(parent.Organism.genome, mate.Organism.genome)
+	child.Organism.traits = InheritTraits(BlendTraits(parent.Organism.traits, mate.Organism.traits), false)
+	child.Organism.verticalGenome = InheritVerticalGenome(parent.Organism.verticalGenome, mate.Organism.verticalGenome)
+	UpdateDirection(&parent.Organism, &child.Organism)
+	MutateGenome(&child.Organism, mutationPrey)
+}
+
+// ReproduceSexually() makes babyShark from shark and mate, the same way ReproducePreySexually() does for prey, with matingCostPredator.
+func (shark *Predator) ReproduceSexually(mate, babyShark *Predator) {
+	//Already check age and energy of both parents!!!!
+	shark.Organism.timeSinceReproduction = 0
+	mate.Organism.timeSinceReproduction = 0
+	shark.Organism.energy -= matingCostPredator
+	mate.Organism.energy -= matingCostPredator
+	curStats.predLedger.mating += 2 * matingCostPredator
+
+	sharkGift := int(float64(shark.Organism.energy) * shark.traits.offspringShare / 2)
+	mateGift := int(float64(mate.Organism.energy) * mate.traits.offspringShare / 2)
+	babyShark.Organism.energy = sharkGift + mateGift
+	shark.Organism.energy -= sharkGift
+	mate.Organism.energy -= mateGift
+	curStats.predLedger.toOffspring += babyShark.Organism.energy
+
+	babyShark.Organism.genome = Crossover(shark.Organism.genome, mate.Organism.genome)
+	babyShark.Organism.traits = InheritTraits(BlendTraits(shark.Organism.traits, mate.Organism.traits), true)
+	babyShark.Organism.verticalGenome = InheritVerticalGenome(shark.Organism.verticalGenome, mate.Organism.verticalGenome)
+	UpdateDirection(&shark.Organism, &babyShark.Organism)
+	MutateGenome(&babyShark.Organism, mutationPredator)
+}
+
+// Crossover() combines two parent genomes into a child genome according to crossoverRule and renormalises it.
+// "uniform" takes every gene from either parent with equal chance, "onePoint" takes the genes before a random cut from genome1 and the rest from genome2,
+// and "blend" takes a random weighted average of the two genomes.
+func Crossover(genome1, genome2 [8]Gene) [8]Gene {
+	var childGenome [8]Gene
+
+	if crossoverRule == "uniform" {
+		for i := range childGenome {
+			if rng.Float64() < 0.5 {
+				childGenome[i] = genome1[i]
+			} else {
+				childGenome[i] = genome2[i]
+			}
+		}
+	} else if crossoverRule == "onePoint" {
+		// cut between 1 and 7 so each parent gives at least one gene
+		cut := 1 + rng.Intn(len(childGenome)-1)
+		for i := range childGenome {
+			if i < cut {
+				childGenome[i] = genome1[i]
+			} else {
+				childGenome[i] = genome2[i]
+			}
+		}
+	} else if crossoverRule == "blend" {
+		weight := Gene(rng.Float64())
+		for i := range childGenome {
+			childGenome[i] = weight*genome1[i] + (1-weight)*genome2[i]
+		}
+	} else {
+		panic("invalid crossoverRule string inputted. should be uniform, onePoint, or blend!")
+	}
+
+	return NormalizeGenome(childGenome)
+}
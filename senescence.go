@@ -0,0 +1,31 @@
+package main
+
+import (
+	"math"
+)
+
+// MortalityCurve is the age-dependent chance of dying of old age for one species. rule picks the curve:
+// "none" never kills, "maxLifespan" kills at exactly maxLifespan generations, "constant" kills with probability hazard every generation,
+// and "gompertz" has a hazard of hazard * exp(gompertzRate * age), so the chance of dying grows exponentially with age.
+type MortalityCurve struct {
+	rule         string
+	maxLifespan  int
+	hazard       float64
+	gompertzRate float64
+}
+
+// DiesOfOldAge() decides at random whether an organism of the given age (generations since birth) dies of old age this generation.
+func (curve MortalityCurve) DiesOfOldAge(age int) bool {
+	if curve.rule == "none" {
+		return false
+	} else if curve.rule == "maxLifespan" {
+		return age >= curve.maxLifespan
+	} else if curve.rule == "constant" {
+		return rng.Float64() < curve.hazard
+	} else if curve.rule == "gompertz" {
+		// turn the hazard rate into the chance of dying within one generation
+		hazardRate := curve.hazard * math.Exp(curve.gompertzRate*float64(age))
+		return rng.Float64() < 1-math.Exp(-hazardRate)
+	}
+	panic("invalid mortality rule string inputted. should be none, maxLifespan, constant, or gompertz!")
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"time"
 )
 
@@ -14,6 +13,12 @@ func SimulateEcosystemEvolution(initialEcosystem *Ecosystem, totalTimesteps int,
 	// initialize the slice to contain all the Ecosystems
 	allEcosystems := make([]*Ecosystem, totalTimesteps+1)
 	allEcosystems[0] = initialEcosystem
+
+	// record the stats of the initial Ecosystem as generation 0
+	ResetStats(0)
+	curStats.preyLedger.introduced, curStats.predLedger.introduced = SpeciesEnergy(initialEcosystem)
+	allStats = []GenerationStats{FinishStats(initialEcosystem)}
+
 	// keep track of start of simulation
 	var start time.Time = time.Now()
 
@@ -22,6 +27,10 @@ func SimulateEcosystemEvolution(initialEcosystem *Ecosystem, totalTimesteps int,
 
 		allEcosystems[i] = UpdateEcosystem(allEcosystems[i-1], foodRule, i)
 
+		// scripted events happen at the end of the generation, so they show up in its stats
+		foodRule = ApplyInterventions(allEcosystems[i], interventions, i, foodRule)
+		allStats = append(allStats, FinishStats(allEcosystems[i]))
+
 		// print status of simulation
 		if (totalTimesteps / 10) != 0 {
 			if i%(totalTimesteps/10) == 0 || i == 1 {
@@ -37,12 +46,29 @@ func SimulateEcosystemEvolution(initialEcosystem *Ecosystem, totalTimesteps int,
 
 func UpdateEcosystem(prevEcosystem *Ecosystem, foodRule string, curGen int) *Ecosystem {
 
+	// the update functions count what happens during this generation into curStats
+	ResetStats(curGen)
+
 	// initialize the nextEcosystem
 	var nextEcosystem *Ecosystem = DeepCopyEcosystem(prevEcosystem)
+	curStats.preyLedger.startEnergy, curStats.predLedger.startEnergy = SpeciesEnergy(nextEcosystem)
+
+	UpdateUnits(nextEcosystem, foodRule, curGen, 1)
+
+	// fishing happens at the end of the generation
+	Harvest(nextEcosystem)
+
+	return nextEcosystem
+}
+
+// UpdateUnits() updates every Unit of nextEcosystem in random order, then the disease and the detritus. food grows with foodLight times
+// its usual probability, so it is 1 in a flat ocean and DepthLight() in a layer of an Ocean, and with the local FoodGrowth().
+func UpdateUnits(nextEcosystem *Ecosystem, foodRule string, curGen int, foodLight float64) {
 
 	// get the numRows and numCols of the ecosystem
 	numRows := nextEcosystem.CountRows()
 	numCols := nextEcosystem.CountCols()
+	UpdateEnvironment(nextEcosystem, curGen)
 	arrayOfIndices := MakeIndicesArray(nextEcosystem)
 
 	// get ready to loop through all the possible indices of the ecosystem using a while loop
@@ -67,7 +93,7 @@ func UpdateEcosystem(prevEcosystem *Ecosystem, foodRule string, curGen int) *Eco
 
 			// skip already updated predator.
 			if (*currentUnit).predator.lastGenUpdated != curGen {
-				currentUnit.predator.UpdatePredator(nextEcosystem, i, j, curGen)
+				currentUnit.predator.UpdatePredator(&LatticeSite{board: nextEcosystem, i: i, j: j, isPredator: true}, curGen)
 			}
 
 		}
@@ -75,13 +101,14 @@ func UpdateEcosystem(prevEcosystem *Ecosystem, foodRule string, curGen int) *Eco
 
 			// skip already updated prey.
 			if (*currentUnit).prey.lastGenUpdated != curGen {
-				UpdatePrey(nextEcosystem, i, j, curGen)
+				UpdatePrey(&LatticeSite{board: nextEcosystem, i: i, j: j}, currentUnit.prey, curGen)
 			}
 
 		}
 
 		// we allow predator and prey stacking on top of food
-		if !(*currentUnit).food.isPresent { // if we made it here that means there can only be food in the current Unit. skip if the food is already true. note: we don't need to check the lastGenUpdated because food will be false if this is ran.
+		growth := foodLight * FoodGrowth(currentUnit)
+		if !(*currentUnit).food.isPresent && (growth >= 1 || rng.Float64() < growth) { // if we made it here that means there can only be food in the current Unit. skip if the food is already true. note: we don't need to check the lastGenUpdated because food will be false if this is ran.
 
 			// determine whether food appears randomly for the prey. GeneratePreyFoodRandomly() will update both fields of the food, if food is generated. otherwise it will leave it false.
 			currentUnit.GeneratePreyFoodProbabilistically(foodRule, i, j, nextEcosystem)
@@ -92,13 +119,20 @@ func UpdateEcosystem(prevEcosystem *Ecosystem, foodRule string, curGen int) *Eco
 		k++
 	}
 
-	return nextEcosystem
+	// the disease spreads once everybody has moved
+	if diseaseEnabled {
+		UpdateDisease(nextEcosystem)
+	}
+
+	if decompositionEnabled {
+		UpdateDetritus(nextEcosystem)
+	}
 }
 
 // Input: the number of rows and number of cols to choose from, numRows and numCols
 // Output: two integers, randomly choosen between for intervals [0,numRows) and [0,numCols)
 func ChooseRandomIndices(numChoices int) int {
-	chosenOrderedPair := rand.Intn(numChoices)
+	chosenOrderedPair := rng.Intn(numChoices)
 
 	return chosenOrderedPair
 }
@@ -165,6 +199,10 @@ func DeepCopyEcosystem(someEcosystem *Ecosystem) *Ecosystem {
 
 			// copy the corresponding fields of the Unit (deep copy)
 			copyEcosystem[i][j].food = (*someEcosystem)[i][j].food
+			copyEcosystem[i][j].detritus = (*someEcosystem)[i][j].detritus
+			copyEcosystem[i][j].refuge = (*someEcosystem)[i][j].refuge // refuges don't change, so the copy shares them
+			copyEcosystem[i][j].temperature = (*someEcosystem)[i][j].temperature
+			copyEcosystem[i][j].light = (*someEcosystem)[i][j].light
 
 			// only attempt to copy if its there
 			if (*someEcosystem)[i][j].prey != nil {
@@ -184,7 +222,14 @@ func DeepCopyEcosystem(someEcosystem *Ecosystem) *Ecosystem {
 func (somePrey *Prey) DeepCopyOrganism() *Prey {
 	var preyCopy Prey
 	preyCopy.age = somePrey.age
+	preyCopy.timeSinceReproduction = somePrey.timeSinceReproduction
+	preyCopy.infection = somePrey.infection
+	preyCopy.infectedFor = somePrey.infectedFor
 	preyCopy.energy = somePrey.energy
+	preyCopy.lastGenUpdated = somePrey.lastGenUpdated
+	preyCopy.lastDirection = somePrey.lastDirection
+	preyCopy.traits = somePrey.traits
+	preyCopy.verticalGenome = somePrey.verticalGenome
 
 	// range over the genome and copy all its genes
 	var copyGenome [8]Gene
@@ -202,7 +247,16 @@ func (somePred *Predator) DeepCopyOrganism() *Predator {
 	var predCopy Predator
 
 	predCopy.age = somePred.age
+	predCopy.timeSinceReproduction = somePred.timeSinceReproduction
+	predCopy.handlingTime = somePred.handlingTime
+	predCopy.gutContents = somePred.gutContents
+	predCopy.infection = somePred.infection
+	predCopy.infectedFor = somePred.infectedFor
 	predCopy.energy = somePred.energy
+	predCopy.lastGenUpdated = somePred.lastGenUpdated
+	predCopy.lastDirection = somePred.lastDirection
+	predCopy.traits = somePred.traits
+	predCopy.verticalGenome = somePred.verticalGenome
 
 	// range over the genome and copy all its genes
 	var copyGenome [8]Gene
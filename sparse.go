@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+)
+
+// Bitset holds one bit per Unit of a SparseEcosystem, indexed by cell (see Cell()).
+type Bitset []uint64
+
+// NewBitset() makes a Bitset of numBits bits, all cleared.
+func NewBitset(numBits int) Bitset {
+	return make(Bitset, (numBits+63)/64)
+}
+
+// Get() says whether bit k is set.
+func (someBitset Bitset) Get(k int) bool {
+	return someBitset[k/64]&(1<<uint(k%64)) != 0
+}
+
+// Set() sets bit k to value.
+func (someBitset Bitset) Set(k int, value bool) {
+	if value {
+		someBitset[k/64] |= 1 << uint(k%64)
+	} else {
+		someBitset[k/64] &^= 1 << uint(k%64)
+	}
+}
+
+// Count() is the number of set bits.
+func (someBitset Bitset) Count() int {
+	count := 0
+	for _, word := range someBitset {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// SparseEcosystem is the second storage backend for the same board as an Ecosystem. Instead of a Unit for every cell it keeps the organisms in
+// maps keyed by their cell (a spatial hash), and the food as a Bitset, so memory and time grow with the number of organisms rather than the size of the board.
+// a cell is row*numCols + col. it is updated by UpdateSparseEcosystem() with the same rules as UpdateEcosystem() (see Board), so 10,000 x 10,000 oceans become practical.
+// it only stores one food type, and no refuges or detritus, so it doesn't support every feature of the dense backend, see CheckSimpleModel()
+type SparseEcosystem struct {
+	numRows   int
+	numCols   int
+	prey      map[int]*Prey
+	predators map[int]*Predator
+	food      Bitset
+}
+
+func (someEcosystem *SparseEcosystem) CountRows() int {
+	return someEcosystem.numRows
+}
+
+func (someEcosystem *SparseEcosystem) CountCols() int {
+	return someEcosystem.numCols
+}
+
+// Cell() is the key of Unit i, j.
+func (someEcosystem *SparseEcosystem) Cell(i, j int) int {
+	return i*someEcosystem.numCols + j
+}
+
+// Location() turns a cell back into the row and column of its Unit.
+func (someEcosystem *SparseEcosystem) Location(cell int) (int, int) {
+	return cell / someEcosystem.numCols, cell % someEcosystem.numCols
+}
+
+// PreyCells() is the cells of the prey in increasing order. ranging over the map visits them in a different order every time,
+// so the prey would draw different numbers from a seeded rng.
+func (someEcosystem *SparseEcosystem) PreyCells() []int {
+	cells := make([]int, 0, len(someEcosystem.prey))
+	for cell := range someEcosystem.prey {
+		cells = append(cells, cell)
+	}
+	sort.Ints(cells)
+	return cells
+}
+
+// PredatorCells() is the cells of the predators in increasing order, see PreyCells().
+func (someEcosystem *SparseEcosystem) PredatorCells() []int {
+	cells := make([]int, 0, len(someEcosystem.predators))
+	for cell := range someEcosystem.predators {
+		cells = append(cells, cell)
+	}
+	sort.Ints(cells)
+	return cells
+}
+
+// DeepCopy() copies someEcosystem with copies of all its organisms, like DeepCopyEcosystem().
+func (someEcosystem *SparseEcosystem) DeepCopy() *SparseEcosystem {
+	newEcosystem := &SparseEcosystem{
+		numRows:   someEcosystem.numRows,
+		numCols:   someEcosystem.numCols,
+		prey:      make(map[int]*Prey, len(someEcosystem.prey)),
+		predators: make(map[int]*Predator, len(someEcosystem.predators)),
+		food:      make(Bitset, len(someEcosystem.food)),
+	}
+	for cell, somePrey := range someEcosystem.prey {
+		newEcosystem.prey[cell] = somePrey.DeepCopyOrganism()
+	}
+	for cell, somePred := range someEcosystem.predators {
+		newEcosystem.predators[cell] = somePred.DeepCopyOrganism()
+	}
+	copy(newEcosystem.food, someEcosystem.food)
+	return newEcosystem
+}
+
+// SpeciesEnergy() returns the total energy held by the prey and by the predators, like SpeciesEnergy() of an Ecosystem.
+func (someEcosystem *SparseEcosystem) SpeciesEnergy() (int, int) {
+	preyEnergy, predEnergy := 0, 0
+	for _, somePrey := range someEcosystem.prey {
+		preyEnergy += somePrey.energy
+	}
+	for _, somePred := range someEcosystem.predators {
+		predEnergy += somePred.energy
+	}
+	return preyEnergy, predEnergy
+}
+
+// ToEcosystem() rasterises someEcosystem into a dense Ecosystem for drawing. the organisms are shared, not copied.
+func (someEcosystem *SparseEcosystem) ToEcosystem() *Ecosystem {
+	newEco := make(Ecosystem, someEcosystem.numRows)
+	for i := range newEco {
+		newEco[i] = make([]*Unit, someEcosystem.numCols)
+		for j := range newEco[i] {
+			newEco[i][j] = new(Unit)
+			newEco[i][j].food.isPresent = someEcosystem.food.Get(someEcosystem.Cell(i, j))
+		}
+	}
+	for cell, somePrey := range someEcosystem.prey {
+		i, j := someEcosystem.Location(cell)
+		newEco[i][j].prey = somePrey
+	}
+	for cell, somePred := range someEcosystem.predators {
+		i, j := someEcosystem.Location(cell)
+		newEco[i][j].predator = somePred
+	}
+	return &newEco
+}
+
+// WriteGenomesToFile() writes the genomes of the organisms of someEcosystem to filename, in the format of WriteGenomesToFile().
+func (someEcosystem *SparseEcosystem) WriteGenomesToFile(filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create genome file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, somePrey := range someEcosystem.prey {
+		WriteGenomeLine(writer, "prey", &somePrey.Organism)
+	}
+	for _, somePred := range someEcosystem.predators {
+		WriteGenomeLine(writer, "predator", &somePred.Organism)
+	}
+
+	if err := writer.Flush(); err != nil {
+		panic("could not write genome file " + filename + ": " + err.Error())
+	}
+}
+
+// Snapshot is a kept generation of an engine that doesn't keep every Ecosystem: a SparseEcosystem or a ContinuousOcean.
+type Snapshot interface {
+	ToEcosystem() *Ecosystem // for drawing
+	WriteGenomesToFile(filename string)
+}
+
+// RasteriseSnapshots() turns snapshots into Ecosystems for AnimateSystem().
+func RasteriseSnapshots(snapshots []Snapshot) []*Ecosystem {
+	var drawnEcosystems []*Ecosystem
+	for _, someSnapshot := range snapshots {
+		drawnEcosystems = append(drawnEcosystems, someSnapshot.ToEcosystem())
+	}
+	return drawnEcosystems
+}
+
+// CheckSimpleModel() panics if the settings use a feature that engine (the sparse backend, or another engine than the lattice) doesn't simulate,
+// so its runs never silently differ from the dense lattice. none of them support several foodTypes, refuges, disease, decomposition, harvesting,
+// depth layers, patches or interventions: a SparseEcosystem only stores the organisms and one food type, and the rest runs over a whole Ecosystem
+// at the end of a generation. an engine onLattice (the sparse backend and gillespie) goes through the per-cell rules of the dense lattice with a Board,
+// so it supports every MovementPolicy and behaviour, perception and flocking. the continuous engine has its own movement, which only supports
+// GenomeWalk, randomWalk and perception without flocking.
+func CheckSimpleModel(engine string, onLattice bool) {
+	_, preyGenomeWalk := movementPrey.(GenomeWalk)
+	_, predGenomeWalk := movementPredator.(GenomeWalk)
+	unsupported := []struct {
+		name string
+		used bool
+	}{
+		{"foodTypes", len(foodTypes) != 0},
+		{"refuges", len(refuges) != 0 || refugeFile != ""},
+		{"diseaseEnabled", diseaseEnabled},
+		{"decompositionEnabled", decompositionEnabled},
+		{"harvestPolicy", harvestPolicy.rule != "none"},
+		{"flockingPrey", flockingPrey && !onLattice},
+		{"behaviourPrey", behaviourPrey != "randomWalk" && !onLattice},
+		{"behaviourPredator", behaviourPredator != "randomWalk" && !onLattice},
+		{"movementPrey", !preyGenomeWalk && !onLattice},
+		{"movementPredator", !predGenomeWalk && !onLattice},
+		{"numLayers", numLayers != 1},
+		{"patches", len(patches) != 0},
+		{"interventionFile", interventionFile != "" || len(interventions) != 0},
+	}
+	for _, feature := range unsupported {
+		if feature.used {
+			panic("the " + engine + " doesn't support " + feature.name + ". use the dense lattice!")
+		}
+	}
+}
+
+// SampleCells() calls visit for every cell below numCells with probability p, in increasing order. it jumps ahead by geometrically distributed gaps,
+// so it takes time in proportion to the number of cells it picks rather than numCells.
+func SampleCells(numCells int, p float64, visit func(cell int)) {
+	if p <= 0 {
+		return
+	}
+	if p >= 1 {
+		for cell := 0; cell < numCells; cell++ {
+			visit(cell)
+		}
+		return
+	}
+
+	logSkip := math.Log(1 - p)
+	cell := -1
+	for {
+		gap := math.Floor(math.Log(1-rng.Float64()) / logSkip)
+		if gap >= float64(numCells-cell) {
+			return
+		}
+		cell += 1 + int(gap)
+		if cell >= numCells {
+			return
+		}
+		visit(cell)
+	}
+}
+
+// InitializeSparseEcosystem() is InitializeEcosystem() for the sparse backend: food in 10% of the Units, and the prey and predators at random Units.
+func InitializeSparseEcosystem(numRows, numCols, numPrey, numPred int) *SparseEcosystem {
+	CheckSimpleModel("sparse storageBackend", true)
+	numCells := numRows * numCols
+	if numPrey > numCells || numPred > numCells {
+		panic("there are more prey or predators than Units in the sparse Ecosystem")
+	}
+
+	newEco := &SparseEcosystem{
+		numRows:   numRows,
+		numCols:   numCols,
+		prey:      make(map[int]*Prey, numPrey),
+		predators: make(map[int]*Predator, numPred),
+		food:      NewBitset(numCells),
+	}
+	SampleCells(numCells, 0.10, func(cell int) {
+		newEco.food.Set(cell, true)
+	})
+
+	for len(newEco.predators) < numPred {
+		cell := rng.Intn(numCells)
+		if newEco.predators[cell] == nil {
+			newEco.predators[cell] = CreatePredator()
+		}
+	}
+	for len(newEco.prey) < numPrey {
+		cell := rng.Intn(numCells)
+		if newEco.prey[cell] == nil && newEco.predators[cell] == nil {
+			newEco.prey[cell] = CreatePrey()
+		}
+	}
+	return newEco
+}
+
+// SimulateSparseEcosystemEvolution() is SimulateEcosystemEvolution() for the sparse backend. a large board can't keep every generation in memory,
+// so it only returns generation 0, every keepEvery-th generation and the last one. the stats of every generation go to allStats
+func SimulateSparseEcosystemEvolution(initialEcosystem *SparseEcosystem, totalTimesteps int, foodRule string, keepEvery int) []*SparseEcosystem {
+	fmt.Println("SimulateSparseEcosystemEvolution is running")
+
+	ResetStats(0)
+	curStats.preyLedger.introduced, curStats.predLedger.introduced = initialEcosystem.SpeciesEnergy()
+	allStats = []GenerationStats{FinishSparseStats(initialEcosystem)}
+
+	kept := []*SparseEcosystem{initialEcosystem}
+	curEcosystem := initialEcosystem
+	for i := 1; i <= totalTimesteps; i++ {
+		curEcosystem = UpdateSparseEcosystem(curEcosystem, foodRule, i)
+		allStats = append(allStats, FinishSparseStats(curEcosystem))
+		if i%keepEvery == 0 || i == totalTimesteps {
+			kept = append(kept, curEcosystem)
+		}
+
+		if (totalTimesteps/10) != 0 && i%(totalTimesteps/10) == 0 {
+			fmt.Println("Simulation is", 100*float64(i)/float64(totalTimesteps), "percent complete. Generation =", i)
+		}
+	}
+	return kept
+}
+
+// cellVisit is the time during a generation at which a cell is updated, see SparseGeneration.
+type cellVisit struct {
+	time float64
+	cell int
+}
+
+// visitQueue is a min-heap of cellVisits by time, for container/heap.
+type visitQueue []cellVisit
+
+func (queue visitQueue) Len() int            { return len(queue) }
+func (queue visitQueue) Less(a, b int) bool  { return queue[a].time < queue[b].time }
+func (queue visitQueue) Swap(a, b int)       { queue[a], queue[b] = queue[b], queue[a] }
+func (queue *visitQueue) Push(x interface{}) { *queue = append(*queue, x.(cellVisit)) }
+func (queue *visitQueue) Pop() interface{} {
+	old := *queue
+	last := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return last
+}
+
+// SparseGeneration is the state of one call of UpdateSparseEcosystem(). UpdateUnits() visits every Unit once in a random order. here every cell gets
+// a random visit time between 0 and 1 instead, which gives the same order, but only the cells where something can happen are given one and put in the queue:
+// the cells with an organism, and the cells where food will grow if they are still empty when they are visited (foodCells).
+// a cell that gets a newborn during the generation is given its time then, and visited if that time hasn't passed yet.
+// it is the Board of the sparse backend, so the organisms are updated by UpdatePrey() and UpdatePredator() like in an Ecosystem.
+type SparseGeneration struct {
+	eco       *SparseEcosystem
+	curGen    int
+	now       float64
+	visits    visitQueue
+	visitTime map[int]float64
+	foodCells map[int]bool
+	born      func(cell int, isPredator bool) // what Born() does with a newborn's cell, Schedule() in a generation of the sparse backend
+}
+
+// UpdateSparseEcosystem() is UpdateEcosystem() for the sparse backend.
+func UpdateSparseEcosystem(prevEcosystem *SparseEcosystem, foodRule string, curGen int) *SparseEcosystem {
+	ResetStats(curGen)
+	nextEcosystem := prevEcosystem.DeepCopy()
+	curStats.preyLedger.startEnergy, curStats.predLedger.startEnergy = nextEcosystem.SpeciesEnergy()
+
+	gen := &SparseGeneration{
+		eco:       nextEcosystem,
+		curGen:    curGen,
+		now:       -1,
+		visitTime: make(map[int]float64),
+		foodCells: make(map[int]bool),
+	}
+	gen.born = func(cell int, isPredator bool) {
+		gen.Schedule(cell)
+	}
+	for _, cell := range nextEcosystem.PreyCells() {
+		gen.Schedule(cell)
+	}
+	for _, cell := range nextEcosystem.PredatorCells() {
+		gen.Schedule(cell)
+	}
+
+	SampleFoodCells(nextEcosystem.numRows, nextEcosystem.numCols, foodRule, curGen, func(cell int) {
+		gen.foodCells[cell] = true
+		gen.Schedule(cell)
+	})
+
+	for gen.visits.Len() > 0 {
+		nextVisit := heap.Pop(&gen.visits).(cellVisit)
+		gen.now = nextVisit.time
+		gen.Visit(nextVisit.cell)
+	}
+
+	return nextEcosystem
+}
+
+// Schedule() gives cell its visit time, if it doesn't have one yet, and queues the visit if that time hasn't passed.
+func (gen *SparseGeneration) Schedule(cell int) {
+	if _, ok := gen.visitTime[cell]; ok {
+		return
+	}
+	visitTime := rng.Float64()
+	gen.visitTime[cell] = visitTime
+	if visitTime > gen.now {
+		heap.Push(&gen.visits, cellVisit{time: visitTime, cell: cell})
+	}
+}
+
+// Visit() updates cell like one step of UpdateUnits(): first the predator, then the prey, then the food.
+func (gen *SparseGeneration) Visit(cell int) {
+	i, j := gen.eco.Location(cell)
+	if shark := gen.eco.predators[cell]; shark != nil && shark.lastGenUpdated != gen.curGen {
+		shark.UpdatePredator(&LatticeSite{board: gen, i: i, j: j, isPredator: true}, gen.curGen)
+	}
+	if currentPrey := gen.eco.prey[cell]; currentPrey != nil && currentPrey.lastGenUpdated != gen.curGen {
+		UpdatePrey(&LatticeSite{board: gen, i: i, j: j}, currentPrey, gen.curGen)
+	}
+	if gen.foodCells[cell] {
+		gen.eco.food.Set(cell, true)
+	}
+}
+
+func (gen *SparseGeneration) CountRows() int {
+	return gen.eco.numRows
+}
+
+func (gen *SparseGeneration) CountCols() int {
+	return gen.eco.numCols
+}
+
+func (gen *SparseGeneration) PreyAt(i, j int) *Prey {
+	return gen.eco.prey[gen.eco.Cell(i, j)]
+}
+
+func (gen *SparseGeneration) PredatorAt(i, j int) *Predator {
+	return gen.eco.predators[gen.eco.Cell(i, j)]
+}
+
+// SetPrey() deletes the key of an emptied cell, so the map only holds the prey.
+func (gen *SparseGeneration) SetPrey(i, j int, somePrey *Prey) {
+	if somePrey == nil {
+		delete(gen.eco.prey, gen.eco.Cell(i, j))
+	} else {
+		gen.eco.prey[gen.eco.Cell(i, j)] = somePrey
+	}
+}
+
+func (gen *SparseGeneration) SetPredator(i, j int, shark *Predator) {
+	if shark == nil {
+		delete(gen.eco.predators, gen.eco.Cell(i, j))
+	} else {
+		gen.eco.predators[gen.eco.Cell(i, j)] = shark
+	}
+}
+
+// FoodAt() is always of the first food type, since the sparse backend only has one.
+func (gen *SparseGeneration) FoodAt(i, j int) Food {
+	return Food{isPresent: gen.eco.food.Get(gen.eco.Cell(i, j))}
+}
+
+func (gen *SparseGeneration) RemoveFood(i, j int) {
+	gen.eco.food.Set(gen.eco.Cell(i, j), false)
+}
+
+// RefugeAt() is always nil, and LeaveCarcass() does nothing: CheckSimpleModel() keeps refuges and decomposition out of the sparse backend.
+func (gen *SparseGeneration) RefugeAt(i, j int) *Refuge {
+	return nil
+}
+
+func (gen *SparseGeneration) LeaveCarcass(i, j, energy int) {}
+
+// ThermalFactor() is UnitThermalFactor() of Unit i, j in generation curGen.
+func (gen *SparseGeneration) ThermalFactor(i, j int) float64 {
+	return UnitThermalFactor(i, j, gen.eco.numRows, gen.curGen)
+}
+
+func (gen *SparseGeneration) Born(i, j int, isPredator bool) {
+	gen.born(gen.eco.Cell(i, j), isPredator)
+}
+
+// SampleFoodCells() calls visit for every cell of a numRows x numCols board where food will grow in generation curGen if the cell is empty when it is visited,
+// each with its UnitFoodProbability(). the cells are picked with the highest probability of foodRule by SampleCells() and then thinned down to their own.
+func SampleFoodCells(numRows, numCols int, foodRule string, curGen int, visit func(cell int)) {
+	maxProbability := MaxFoodProbability(foodRule)
+	SampleCells(numRows*numCols, maxProbability, func(cell int) {
+		if rng.Float64()*maxProbability < UnitFoodProbability(foodRule, cell/numCols, cell%numCols, numRows, numCols, curGen) {
+			visit(cell)
+		}
+	})
+}
+
+// FinishSparseStats() is FinishStats() for the sparse backend. meanTemperature is left at 0, since it would mean visiting every Unit.
+func FinishSparseStats(someEcosystem *SparseEcosystem) GenerationStats {
+	stats := curStats
+	stats.meanPreyTraits = make([]float64, len(traitNames))
+	stats.meanPredTraits = make([]float64, len(traitNames))
+	stats.meanPreyDiet = make([]float64, NumFoodTypes())
+
+	var organisms []*Organism
+	for cell, shark := range someEcosystem.predators {
+		organisms = append(organisms, &shark.Organism)
+		row, _ := someEcosystem.Location(cell)
+		stats.numPred++
+		stats.meanPredRow += float64(row)
+		if !shark.CanEat() {
+			stats.numHandlingPred++
+		}
+		AddTraitValues(stats.meanPredTraits, shark.traits)
+	}
+	// the prey neighbours are counted on a Board of someEcosystem, like in an Ecosystem
+	board := &SparseGeneration{eco: someEcosystem}
+	totalNeighbours := 0
+	for cell, currentPrey := range someEcosystem.prey {
+		organisms = append(organisms, &currentPrey.Organism)
+		row, col := someEcosystem.Location(cell)
+		stats.numPrey++
+		stats.meanPreyRow += float64(row)
+		AddTraitValues(stats.meanPreyTraits, currentPrey.traits)
+		for foodType, preference := range currentPrey.traits.diet {
+			stats.meanPreyDiet[foodType] += preference
+		}
+		stats.dietSpecialisation += DietSpecialisation(currentPrey.traits.diet)
+		if neighbours := CountPreyNeighbours(board, row, col); neighbours > 0 {
+			stats.numSchooledPrey++
+			totalNeighbours += neighbours
+		}
+	}
+	for _, someOrganism := range organisms {
+		if someOrganism.infection == susceptible {
+			stats.numSusceptible++
+		} else if someOrganism.infection == infected {
+			stats.numInfected++
+		} else {
+			stats.numRecovered++
+		}
+	}
+
+	numUnits := someEcosystem.numRows * someEcosystem.numCols
+	stats.numFood = someEcosystem.food.Count()
+	stats.preyClustering = ClusteringIndex(totalNeighbours, stats.numPrey, numUnits)
+	_, heldEnergy := FoodEnergy(0)
+	stats.preyLedger.endEnergy, stats.predLedger.endEnergy = someEcosystem.SpeciesEnergy()
+	stats.organismEnergy = stats.preyLedger.endEnergy + stats.predLedger.endEnergy
+	stats.foodEnergy = stats.numFood * heldEnergy
+	CheckLedgers(stats)
+
+	AverageStats(&stats, numUnits)
+
+	return stats
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+// GenerationStats holds everything we record about one generation of the simulation. it is written as one row of the stats file.
+type GenerationStats struct {
+	generation int
+	numPrey    int
+	numPred    int
+	numFood    int
+
+	// counted by the update functions while the generation runs
+	preyEaten         int // prey killed by predators
+	foodEaten         int // food eaten by prey
+	schooledPreyEaten int // prey killed by predators while they had at least one prey neighbour
+	preyStarved       int // prey that ran out of energy
+	preyDiedOfAge     int // prey killed by mortalityPrey
+	predStarved       int
+	predDiedOfAge     int
+	preyDiedOfDisease int
+	predDiedOfDisease int
+	preyHarvested     int // fishing yield, see Harvest()
+	predHarvested     int
+	preyYieldEnergy   int // total energy of the prey that were caught
+	predYieldEnergy   int
+
+	preyLedger EnergyLedger // where the energy of each species came from and went, see EnergyLedger
+	predLedger EnergyLedger
+
+	preyKilledByIntervention int // see KillRegion()
+	predKilledByIntervention int
+
+	preyEmigrated  int // organisms that left for another patch, see EmigrateOrganisms()
+	predEmigrated  int
+	preyImmigrated int // organisms that arrived from another patch, see ArriveMigrants()
+	predImmigrated int
+	migrantsLost   int // migrants that found no free Unit when they arrived
+
+	// depth, see Ocean. all 0 in a flat ocean, which is always day
+	isNight           bool
+	meanPreyDepth     float64 // mean layer of the prey, 0 at the surface
+	meanPredDepth     float64
+	preyDielMigration float64 // mean DielMigration() of the prey, which is above 0 once prey dive by day and rise at night
+	predDielMigration float64
+
+	// temperature, see EnvironmentField. the mean row of a species shows its range shifting along a "gradient" field
+	meanTemperature float64
+	meanPreyRow     float64
+	meanPredRow     float64
+
+	carcassEnergy         int // energy left behind by dead organisms, see DepositCarcass()
+	planktonFromNutrients int // plankton grown out of decayed detritus
+
+	// counted from the Ecosystem at the end of the generation, see TotalEnergy()
+	organismEnergy int
+	foodEnergy     int
+	detritusEnergy int
+
+	// counted from the Ecosystem at the end of the generation
+	numSchooledPrey  int     // prey with at least one prey among their 8 neighbours
+	numShelteredPrey int     // prey inside a refuge
+	preyClustering   float64 // see PreyClustering()
+	preyDensity      float64 // prey per Unit
+	killRate         float64 // prey eaten per predator during the generation, for the functional response
+	foragingRate     float64 // food eaten per prey during the generation, to compare foraging efficiency between MovementPolicies
+	numHandlingPred  int     // predators that can't eat because they are handling a kill or are full
+	numSusceptible   int     // SIR counts over prey and predators together
+	numInfected      int
+	numRecovered     int
+	meanPreyTraits   []float64 // mean of every heritable trait, in the order of traitNames
+	meanPredTraits   []float64
+	meanPreyDiet     []float64 // mean preference of the prey for each food type
+	// mean of the highest diet preference of every prey, from 1/NumFoodTypes() when all prey are generalists to 1 when all are specialists
+	dietSpecialisation float64
+}
+
+// curStats is filled in by the update functions during the current generation, allStats has one entry per generation of the last simulation.
+var curStats GenerationStats
+var allStats []GenerationStats
+
+// ResetStats() clears the counters before generation curGen is simulated.
+func ResetStats(curGen int) {
+	curStats = GenerationStats{generation: curGen}
+}
+
+// FinishStats() adds the counts and metrics taken from someEcosystem to the counters of the current generation and returns them.
+func FinishStats(someEcosystem *Ecosystem) GenerationStats {
+	stats := curStats
+	stats.meanPreyTraits = make([]float64, len(traitNames))
+	stats.meanPredTraits = make([]float64, len(traitNames))
+	stats.meanPreyDiet = make([]float64, NumFoodTypes())
+
+	for _, someOrganism := range AllOrganisms(someEcosystem) {
+		if someOrganism.infection == susceptible {
+			stats.numSusceptible++
+		} else if someOrganism.infection == infected {
+			stats.numInfected++
+		} else {
+			stats.numRecovered++
+		}
+	}
+
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			curUnit := (*someEcosystem)[i][j]
+			stats.meanTemperature += curUnit.temperature
+			if curUnit.food.isPresent {
+				stats.numFood++
+			}
+			if curUnit.predator != nil {
+				stats.numPred++
+				stats.meanPredRow += float64(i)
+				if !curUnit.predator.CanEat() {
+					stats.numHandlingPred++
+				}
+				AddTraitValues(stats.meanPredTraits, curUnit.predator.traits)
+			}
+			if curUnit.prey != nil {
+				stats.numPrey++
+				stats.meanPreyRow += float64(i)
+				AddTraitValues(stats.meanPreyTraits, curUnit.prey.traits)
+				for foodType, preference := range curUnit.prey.traits.diet {
+					stats.meanPreyDiet[foodType] += preference
+				}
+				stats.dietSpecialisation += DietSpecialisation(curUnit.prey.traits.diet)
+				if CountPreyNeighbours(someEcosystem, i, j) > 0 {
+					stats.numSchooledPrey++
+				}
+			}
+		}
+	}
+	stats.preyClustering = PreyClustering(someEcosystem, stats.numPrey)
+	stats.numShelteredPrey = CountShelteredPrey(someEcosystem)
+	stats.organismEnergy, stats.foodEnergy, stats.detritusEnergy = TotalEnergy(someEcosystem)
+	stats.preyLedger.endEnergy, stats.predLedger.endEnergy = SpeciesEnergy(someEcosystem)
+	CheckLedgers(stats)
+	stats.meanTemperature /= float64(someEcosystem.CountRows() * someEcosystem.CountCols())
+	AverageStats(&stats, someEcosystem.CountRows()*someEcosystem.CountCols())
+
+	return stats
+}
+
+// AverageStats() turns the sums FinishStats() adds up over the organisms of an Ecosystem with numUnits Units into means and rates.
+func AverageStats(stats *GenerationStats, numUnits int) {
+	stats.preyDensity = float64(stats.numPrey) / float64(numUnits)
+	if stats.numPrey != 0 {
+		stats.foragingRate = float64(stats.foodEaten) / float64(stats.numPrey)
+	}
+	if stats.numPred != 0 {
+		stats.killRate = float64(stats.preyEaten) / float64(stats.numPred)
+	}
+
+	if stats.numPrey != 0 {
+		stats.meanPreyRow /= float64(stats.numPrey)
+	}
+	if stats.numPred != 0 {
+		stats.meanPredRow /= float64(stats.numPred)
+	}
+
+	// turn the sums of the traits into means
+	for foodType := range stats.meanPreyDiet {
+		if stats.numPrey != 0 {
+			stats.meanPreyDiet[foodType] /= float64(stats.numPrey)
+		}
+	}
+	if stats.numPrey != 0 {
+		stats.dietSpecialisation /= float64(stats.numPrey)
+	}
+	for k := range traitNames {
+		if stats.numPrey != 0 {
+			stats.meanPreyTraits[k] /= float64(stats.numPrey)
+		}
+		if stats.numPred != 0 {
+			stats.meanPredTraits[k] /= float64(stats.numPred)
+		}
+	}
+}
+
+// AddTraitValues() adds the values of someTraits to the running sums in traitSums.
+func AddTraitValues(traitSums []float64, someTraits Traits) {
+	for k, value := range TraitValues(someTraits) {
+		traitSums[k] += value
+	}
+}
+
+// CountPreyNeighbours() counts the prey among the 8 neighbours of Unit i, j (wrapping around the edges).
+func CountPreyNeighbours(board Board, i, j int) int {
+	numRows := board.CountRows()
+	numCols := board.CountCols()
+	count := 0
+	for _, moveDeltas := range deltas {
+		if board.PreyAt(GetIndex(i, moveDeltas.row, numRows), GetIndex(j, moveDeltas.col, numCols)) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// PreyClustering() is the mean number of prey neighbours per prey, divided by the number we'd expect if the same prey were scattered at random.
+// 1 means no schooling, above 1 means prey are clumped together, below 1 means they keep apart. it is 0 if there are fewer than 2 prey.
+func PreyClustering(someEcosystem *Ecosystem, numPrey int) float64 {
+	numUnits := someEcosystem.CountRows() * someEcosystem.CountCols()
+	if numPrey < 2 || numUnits < 2 {
+		return 0
+	}
+
+	totalNeighbours := 0
+	for i := range *someEcosystem {
+		for j := range (*someEcosystem)[i] {
+			if (*someEcosystem)[i][j].prey != nil {
+				totalNeighbours += CountPreyNeighbours(someEcosystem, i, j)
+			}
+		}
+	}
+	return ClusteringIndex(totalNeighbours, numPrey, numUnits)
+}
+
+// ClusteringIndex() is PreyClustering() for numPrey prey with totalNeighbours prey neighbours between them, on numUnits Units.
+func ClusteringIndex(totalNeighbours, numPrey, numUnits int) float64 {
+	if numPrey < 2 || numUnits < 2 {
+		return 0
+	}
+	observed := float64(totalNeighbours) / float64(numPrey)
+	expected := 8 * float64(numPrey-1) / float64(numUnits-1)
+	return observed / expected
+}
+
+// StatsHeader() returns the column names of the stats file, in the same order as StatsRow().
+func StatsHeader() []string {
+	header := []string{
+		"generation", "numPrey", "numPred", "numFood",
+		"preyEaten", "schooledPreyEaten", "numSchooledPrey", "preyClustering",
+		"preyStarved", "preyDiedOfAge", "predStarved", "predDiedOfAge",
+		"preyDensity", "killRate", "numHandlingPred", "foodEaten", "foragingRate", "numShelteredPrey",
+		"numSusceptible", "numInfected", "numRecovered", "preyDiedOfDisease", "predDiedOfDisease",
+		"preyHarvested", "predHarvested", "preyYieldEnergy", "predYieldEnergy",
+		"preyKilledByIntervention", "predKilledByIntervention",
+		"preyEmigrated", "predEmigrated", "preyImmigrated", "predImmigrated", "migrantsLost",
+		"isNight", "meanPreyDepth", "meanPredDepth", "preyDielMigration", "predDielMigration",
+		"meanTemperature", "meanPreyRow", "meanPredRow",
+		"carcassEnergy", "planktonFromNutrients", "organismEnergy", "foodEnergy", "detritusEnergy",
+	}
+	for _, name := range traitNames {
+		header = append(header, "meanPrey_"+name)
+	}
+	for _, name := range traitNames {
+		header = append(header, "meanPred_"+name)
+	}
+	for foodType := 0; foodType < NumFoodTypes(); foodType++ {
+		header = append(header, "meanPreyDiet_"+FoodTypeName(foodType))
+	}
+	header = append(header, "dietSpecialisation")
+	return header
+}
+
+// StatsRow() formats stats as one row of the stats file.
+func StatsRow(stats GenerationStats) []string {
+	row := []string{
+		strconv.Itoa(stats.generation), strconv.Itoa(stats.numPrey), strconv.Itoa(stats.numPred), strconv.Itoa(stats.numFood),
+		strconv.Itoa(stats.preyEaten), strconv.Itoa(stats.schooledPreyEaten), strconv.Itoa(stats.numSchooledPrey), strconv.FormatFloat(stats.preyClustering, 'f', 4, 64),
+		strconv.Itoa(stats.preyStarved), strconv.Itoa(stats.preyDiedOfAge), strconv.Itoa(stats.predStarved), strconv.Itoa(stats.predDiedOfAge),
+		strconv.FormatFloat(stats.preyDensity, 'f', 4, 64), strconv.FormatFloat(stats.killRate, 'f', 4, 64), strconv.Itoa(stats.numHandlingPred), strconv.Itoa(stats.foodEaten), strconv.FormatFloat(stats.foragingRate, 'f', 4, 64), strconv.Itoa(stats.numShelteredPrey),
+		strconv.Itoa(stats.numSusceptible), strconv.Itoa(stats.numInfected), strconv.Itoa(stats.numRecovered), strconv.Itoa(stats.preyDiedOfDisease), strconv.Itoa(stats.predDiedOfDisease),
+		strconv.Itoa(stats.preyHarvested), strconv.Itoa(stats.predHarvested), strconv.Itoa(stats.preyYieldEnergy), strconv.Itoa(stats.predYieldEnergy),
+		strconv.Itoa(stats.preyKilledByIntervention), strconv.Itoa(stats.predKilledByIntervention),
+		strconv.Itoa(stats.preyEmigrated), strconv.Itoa(stats.predEmigrated), strconv.Itoa(stats.preyImmigrated), strconv.Itoa(stats.predImmigrated), strconv.Itoa(stats.migrantsLost),
+		strconv.FormatBool(stats.isNight), strconv.FormatFloat(stats.meanPreyDepth, 'f', 4, 64), strconv.FormatFloat(stats.meanPredDepth, 'f', 4, 64), strconv.FormatFloat(stats.preyDielMigration, 'f', 4, 64), strconv.FormatFloat(stats.predDielMigration, 'f', 4, 64),
+		strconv.FormatFloat(stats.meanTemperature, 'f', 4, 64), strconv.FormatFloat(stats.meanPreyRow, 'f', 4, 64), strconv.FormatFloat(stats.meanPredRow, 'f', 4, 64),
+		strconv.Itoa(stats.carcassEnergy), strconv.Itoa(stats.planktonFromNutrients), strconv.Itoa(stats.organismEnergy), strconv.Itoa(stats.foodEnergy), strconv.Itoa(stats.detritusEnergy),
+	}
+	for _, value := range stats.meanPreyTraits {
+		row = append(row, strconv.FormatFloat(value, 'f', 4, 64))
+	}
+	for _, value := range stats.meanPredTraits {
+		row = append(row, strconv.FormatFloat(value, 'f', 4, 64))
+	}
+	for _, value := range stats.meanPreyDiet {
+		row = append(row, strconv.FormatFloat(value, 'f', 4, 64))
+	}
+	row = append(row, strconv.FormatFloat(stats.dietSpecialisation, 'f', 4, 64))
+	return row
+}
+
+// WriteStatsToFile() writes one row per generation of allStats to filename as a CSV file.
+func WriteStatsToFile(allStats []GenerationStats, filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic("could not create stats file " + filename + ": " + err.Error())
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(StatsHeader())
+	for _, stats := range allStats {
+		writer.Write(StatsRow(stats))
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		panic("could not write stats file " + filename + ": " + err.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+)
+
+// Traits are the heritable life-history traits of an organism, next to its direction genome.
+// A child gets its parent's traits with mutation (see InheritTraits()), so the traits can evolve.
+type Traits struct {
+	speed            int     // cells moved per generation
+	visionRadius     int     // how far away food, prey and predators can be sensed or hunted
+	metabolism       int     // basal energy cost per generation
+	energyThreshold  int     // energy needed to reproduce
+	ageThreshold     int     // generations since birth or the last reproduction needed to reproduce
+	offspringShare   float64 // share of the parent's energy given to the child
+	foodAttraction   float64 // strength of the pull towards sensed food
+	predatorAversion float64 // strength of the push away from sensed predators
+
+	// preference for each food type, adding up to 1. see DietEfficiency(). only prey have a diet, and it is never changed in place
+	diet []float64
+}
+
+// traitNames are the names of the traits in the order TraitValues() returns them, used for the stats columns.
+var traitNames = []string{"speed", "visionRadius", "metabolism", "energyThreshold", "ageThreshold", "offspringShare", "foodAttraction", "predatorAversion"}
+
+// FounderTraitsPrey() returns the traits every prey of the founding population starts with, taken from the global prey parameters.
+func FounderTraitsPrey() Traits {
+	return Traits{
+		speed:            speedPrey,
+		visionRadius:     visionRadiusPrey,
+		metabolism:       costOfLivingPrey,
+		energyThreshold:  energyThresholdPrey,
+		ageThreshold:     ageThresholdPrey,
+		offspringShare:   offspringSharePrey,
+		foodAttraction:   foodAttractionPrey,
+		predatorAversion: predatorAversionPrey,
+		diet:             FounderDiet(),
+	}
+}
+
+// FounderTraitsPredator() returns the traits every predator of the founding population starts with, taken from the global predator parameters.
+func FounderTraitsPredator() Traits {
+	return Traits{
+		speed:           speedPredator,
+		visionRadius:    visionRadiusPredator,
+		metabolism:      costOfLivingPredator,
+		energyThreshold: energyThresholdPredator,
+		ageThreshold:    ageThresholdPredator,
+		offspringShare:  offspringSharePredator,
+	}
+}
+
+// InheritTraits() returns a child's traits: parentTraits with every trait mutated by traitMutationStrength.
+// Integer traits get noise relative to their size, so an energy threshold of 100 changes by more than a speed of 1.
+// The speed of a species whose speed doesn't evolve (see speedEvolvesPrey) is always its founder speed.
+// A founder visionRadius or metabolism of 0 means sensing or the basal cost is off for the species, so those traits only evolve when their founder value isn't 0.
+func InheritTraits(parentTraits Traits, isPredator bool) Traits {
+	speed := MutateIntTrait(parentTraits.speed, traitMutationStrength, 1)
+	founderVisionRadius, founderMetabolism := visionRadiusPrey, costOfLivingPrey
+	if isPredator {
+		founderVisionRadius, founderMetabolism = visionRadiusPredator, costOfLivingPredator
+	}
+	if isPredator && !speedEvolvesPredator {
+		speed = speedPredator
+	} else if !isPredator && !speedEvolvesPrey {
+		speed = speedPrey
+	}
+
+	return Traits{
+		speed:            speed,
+		visionRadius:     MutateSwitchableTrait(parentTraits.visionRadius, founderVisionRadius),
+		metabolism:       MutateSwitchableTrait(parentTraits.metabolism, founderMetabolism),
+		energyThreshold:  MutateIntTrait(parentTraits.energyThreshold, traitMutationStrength, 1),
+		ageThreshold:     MutateIntTrait(parentTraits.ageThreshold, traitMutationStrength, 1),
+		offspringShare:   math.Min(math.Max(MutateTrait(parentTraits.offspringShare, traitMutationStrength/10), 0.05), 0.95),
+		foodAttraction:   MutateTrait(parentTraits.foodAttraction, traitMutationStrength),
+		predatorAversion: MutateTrait(parentTraits.predatorAversion, traitMutationStrength),
+		diet:             InheritDiet(parentTraits.diet),
+	}
+}
+
+// BlendTraits() averages the traits of two parents, for sexual reproduction. InheritTraits() is applied to the result.
+func BlendTraits(traits1, traits2 Traits) Traits {
+	return Traits{
+		speed:            (traits1.speed + traits2.speed + rng.Intn(2)) / 2,
+		visionRadius:     (traits1.visionRadius + traits2.visionRadius + rng.Intn(2)) / 2,
+		metabolism:       (traits1.metabolism + traits2.metabolism + rng.Intn(2)) / 2,
+		energyThreshold:  (traits1.energyThreshold + traits2.energyThreshold + rng.Intn(2)) / 2,
+		ageThreshold:     (traits1.ageThreshold + traits2.ageThreshold + rng.Intn(2)) / 2,
+		offspringShare:   (traits1.offspringShare + traits2.offspringShare) / 2,
+		foodAttraction:   (traits1.foodAttraction + traits2.foodAttraction) / 2,
+		predatorAversion: (traits1.predatorAversion + traits2.predatorAversion) / 2,
+		diet:             BlendDiet(traits1.diet, traits2.diet),
+	}
+}
+
+// MutateTrait() returns a child's copy of a heritable trait: the parent's value plus Gaussian noise with standard deviation strength, never below 0.
+func MutateTrait(value, strength float64) float64 {
+	value += rng.NormFloat64() * strength
+	if value < 0 {
+		value = 0
+	}
+	return value
+}
+
+// MutateIntTrait() adds Gaussian noise with standard deviation strength * value (at least strength) to an integer trait and never goes below minimum.
+// The result is rounded up or down at random in proportion to the fraction, so small traits like speed can still change and the mutation isn't biased.
+// A result below minimum is reflected back across minimum - 1/2, so minimum - 1 becomes minimum, minimum - 2 becomes minimum + 1 and so on.
+// Reflecting rather than clamping keeps the mutation a symmetric random walk with a reflecting bound, instead of piling values up at minimum.
+func MutateIntTrait(value int, strength float64, minimum int) int {
+	newValue := float64(value) + rng.NormFloat64()*strength*math.Max(float64(value), 1)
+	rounded := math.Floor(newValue)
+	if rng.Float64() < newValue-rounded {
+		rounded++
+	}
+
+	if int(rounded) < minimum {
+		return 2*minimum - 1 - int(rounded)
+	}
+	return int(rounded)
+}
+
+// MutateSwitchableTrait() is MutateIntTrait() for a trait whose founder value of 0 switches it off, like a visionRadius or metabolism of 0.
+// Such a trait stays 0, so sensing or a basal cost can't appear by mutation in a species that was set up without it.
+func MutateSwitchableTrait(value, founderValue int) int {
+	if founderValue == 0 {
+		return value
+	}
+	return MutateIntTrait(value, traitMutationStrength, 0)
+}
+
+// TraitValues() returns the traits as float64 in the order of traitNames.
+func TraitValues(someTraits Traits) []float64 {
+	return []float64{
+		float64(someTraits.speed), float64(someTraits.visionRadius), float64(someTraits.metabolism), float64(someTraits.energyThreshold),
+		float64(someTraits.ageThreshold), someTraits.offspringShare, someTraits.foodAttraction, someTraits.predatorAversion,
+	}
+}